@@ -14,6 +14,8 @@ const TAB = "  "
 type Node interface {
 	TokenLiteral() string
 	String() string
+	Pos() token.Position // position of the first char of the node
+	End() token.Position // position immediately after the last char of the node
 }
 
 // Expression a single program expression
@@ -37,9 +39,36 @@ type Expressions []Expression
 // Statements list of statements
 type Statements []Statement
 
+// posOf returns the start position of the node, or that of tok if the node is missing
+func posOf(node Node, tok token.Token) token.Position {
+	if node == nil {
+		return tok.Pos
+	}
+	return node.Pos()
+}
+
+// endOf returns the end position of the node, or that of tok if the node is missing
+func endOf(node Node, tok token.Token) token.Position {
+	if node == nil {
+		return tok.End
+	}
+	return node.End()
+}
+
 // Program root of the AST
 type Program struct {
 	Statements Statements
+	Comments   []token.Comment // every comment in the source, in order; ignored by evaluation
+
+	// the comments attached to the statements having any, nested statements included
+	Attached map[Statement]*StatementComments
+}
+
+// StatementComments the comments attached to a statement: those on the lines before it,
+// and those starting on its last line, after it
+type StatementComments struct {
+	Leading  []token.Comment
+	Trailing []token.Comment
 }
 
 // TokenLiteral the literal value of the token
@@ -50,6 +79,22 @@ func (p *Program) TokenLiteral() string {
 	return ""
 }
 
+// Pos the position of the first statement of the program
+func (p *Program) Pos() token.Position {
+	if len(p.Statements) > 0 {
+		return p.Statements[0].Pos()
+	}
+	return token.Position{}
+}
+
+// End the end position of the last statement of the program
+func (p *Program) End() token.Position {
+	if len(p.Statements) > 0 {
+		return p.Statements[len(p.Statements)-1].End()
+	}
+	return token.Position{}
+}
+
 // String string representation of the program
 func (p *Program) String() string {
 	var out bytes.Buffer
@@ -61,9 +106,10 @@ func (p *Program) String() string {
 
 // LetStatement represents a let statement in the AST
 type LetStatement struct {
-	Token token.Token // the token.LET token
-	Name  *Identifier
-	Value Expression
+	Token   token.Token // the token.LET token
+	Name    *Identifier
+	Pattern Expression // an ArrayPattern or a HashPattern, destructuring the value; nil if Name is set
+	Value   Expression
 }
 
 func (ls *LetStatement) statementNode() {}
@@ -71,11 +117,32 @@ func (ls *LetStatement) statementNode() {}
 // TokenLiteral the literal value of the let statement token
 func (ls *LetStatement) TokenLiteral() string { return ls.Token.Literal }
 
+// Pos the position of the let keyword
+func (ls *LetStatement) Pos() token.Position { return ls.Token.Pos }
+
+// End the end position of the assigned value
+func (ls *LetStatement) End() token.Position {
+	if ls.Value != nil {
+		return ls.Value.End()
+	}
+	if ls.Name != nil {
+		return ls.Name.End()
+	}
+	if ls.Pattern != nil {
+		return ls.Pattern.End()
+	}
+	return ls.Token.End
+}
+
 // String string representation of a let statement
 func (ls *LetStatement) String() string {
 	var out bytes.Buffer
 	out.WriteString(ls.TokenLiteral() + " ")
-	out.WriteString(ls.Name.String())
+	if ls.Pattern != nil {
+		out.WriteString(ls.Pattern.String())
+	} else {
+		out.WriteString(ls.Name.String())
+	}
 	out.WriteString(" = ")
 	if ls.Value != nil {
 		out.WriteString(ls.Value.String())
@@ -85,6 +152,89 @@ func (ls *LetStatement) String() string {
 	return out.String()
 }
 
+// PatternElement represents an element of a destructuring pattern: the Identifier or the
+// nested pattern bound to a part of the value, with the default used when it is missing
+type PatternElement struct {
+	Key     *Identifier // the member name, in a hash pattern
+	Target  Expression
+	Default Expression // nil if none
+}
+
+// String string representation of a pattern element
+func (pe *PatternElement) String() string {
+	var out bytes.Buffer
+	if pe.Key != nil {
+		out.WriteString(pe.Key.String())
+		if ident, ok := pe.Target.(*Identifier); !ok || ident.Value != pe.Key.Value {
+			out.WriteString(": " + pe.Target.String())
+		}
+	} else {
+		out.WriteString(pe.Target.String())
+	}
+	if pe.Default != nil {
+		out.WriteString(" = " + pe.Default.String())
+	}
+	return out.String()
+}
+
+// ArrayPattern represents a destructuring of the elements of an array: [a, b = 1, ...rest]
+type ArrayPattern struct {
+	Token    token.Token // the '[' token
+	Elements []*PatternElement
+	Rest     *Identifier // collects the remaining elements; nil if none
+	EndToken token.Token // the ']' token
+}
+
+func (ap *ArrayPattern) expressionNode() {}
+
+// TokenLiteral the literal value of the array pattern token
+func (ap *ArrayPattern) TokenLiteral() string { return ap.Token.Literal }
+
+// Pos the position of the '[' token
+func (ap *ArrayPattern) Pos() token.Position { return ap.Token.Pos }
+
+// End the end position of the ']' token
+func (ap *ArrayPattern) End() token.Position { return ap.EndToken.End }
+
+// String string representation of an array pattern
+func (ap *ArrayPattern) String() string {
+	elements := []string{}
+	for _, el := range ap.Elements {
+		elements = append(elements, el.String())
+	}
+	if ap.Rest != nil {
+		elements = append(elements, "..."+ap.Rest.String())
+	}
+	return "[" + strings.Join(elements, ", ") + "]"
+}
+
+// HashPattern represents a destructuring of the members of a hash: {name, age: years}
+type HashPattern struct {
+	Token    token.Token // the '{' token
+	Elements []*PatternElement
+	EndToken token.Token // the '}' token
+}
+
+func (hp *HashPattern) expressionNode() {}
+
+// TokenLiteral the literal value of the hash pattern token
+func (hp *HashPattern) TokenLiteral() string { return hp.Token.Literal }
+
+// Pos the position of the '{' token
+func (hp *HashPattern) Pos() token.Position { return hp.Token.Pos }
+
+// End the end position of the '}' token
+func (hp *HashPattern) End() token.Position { return hp.EndToken.End }
+
+// String string representation of a hash pattern
+func (hp *HashPattern) String() string {
+	elements := []string{}
+	for _, el := range hp.Elements {
+		elements = append(elements, el.String())
+	}
+	return "{" + strings.Join(elements, ", ") + "}"
+}
+
 // ReturnStatement represents a return statement in the AST
 type ReturnStatement struct {
 	Token token.Token // the token.RETURN token
@@ -96,6 +246,12 @@ func (rs *ReturnStatement) statementNode() {}
 // TokenLiteral the literal value of the return statement token
 func (rs *ReturnStatement) TokenLiteral() string { return rs.Token.Literal }
 
+// Pos the position of the return keyword
+func (rs *ReturnStatement) Pos() token.Position { return rs.Token.Pos }
+
+// End the end position of the returned value
+func (rs *ReturnStatement) End() token.Position { return endOf(rs.Value, rs.Token) }
+
 // String string representation of a return statement
 func (rs *ReturnStatement) String() string {
 	var out bytes.Buffer
@@ -118,6 +274,12 @@ func (es *ExpressionStatement) statementNode() {}
 // TokenLiteral the literal value of the expression statement token
 func (es *ExpressionStatement) TokenLiteral() string { return es.Token.Literal }
 
+// Pos the start position of the expression
+func (es *ExpressionStatement) Pos() token.Position { return posOf(es.Expression, es.Token) }
+
+// End the end position of the expression
+func (es *ExpressionStatement) End() token.Position { return endOf(es.Expression, es.Token) }
+
 // String string representation of an expression statement
 func (es *ExpressionStatement) String() string {
 	if es.Expression != nil {
@@ -126,10 +288,70 @@ func (es *ExpressionStatement) String() string {
 	return ""
 }
 
+// BreakStatement represents a break statement in the AST
+type BreakStatement struct {
+	Token token.Token // the token.BREAK token
+}
+
+func (bs *BreakStatement) statementNode() {}
+
+// TokenLiteral the literal value of the break statement token
+func (bs *BreakStatement) TokenLiteral() string { return bs.Token.Literal }
+
+// Pos the position of the break keyword
+func (bs *BreakStatement) Pos() token.Position { return bs.Token.Pos }
+
+// End the end position of the break keyword
+func (bs *BreakStatement) End() token.Position { return bs.Token.End }
+
+// String string representation of a break statement
+func (bs *BreakStatement) String() string { return bs.TokenLiteral() + ";" }
+
+// ContinueStatement represents a continue statement in the AST
+type ContinueStatement struct {
+	Token token.Token // the token.CONTINUE token
+}
+
+func (cs *ContinueStatement) statementNode() {}
+
+// TokenLiteral the literal value of the continue statement token
+func (cs *ContinueStatement) TokenLiteral() string { return cs.Token.Literal }
+
+// Pos the position of the continue keyword
+func (cs *ContinueStatement) Pos() token.Position { return cs.Token.Pos }
+
+// End the end position of the continue keyword
+func (cs *ContinueStatement) End() token.Position { return cs.Token.End }
+
+// String string representation of a continue statement
+func (cs *ContinueStatement) String() string { return cs.TokenLiteral() + ";" }
+
+// FunctionStatement represents a named function declaration: fn add(a, b) { a + b }
+type FunctionStatement struct {
+	Token    token.Token // the token.FUNCTION token
+	Name     *Identifier
+	Function *FunctionLiteral
+}
+
+func (fs *FunctionStatement) statementNode() {}
+
+// TokenLiteral the literal value of the function statement token
+func (fs *FunctionStatement) TokenLiteral() string { return fs.Token.Literal }
+
+// Pos the position of the 'fn' token
+func (fs *FunctionStatement) Pos() token.Position { return fs.Token.Pos }
+
+// End the end position of the function body
+func (fs *FunctionStatement) End() token.Position { return fs.Function.End() }
+
+// String string representation of a function statement
+func (fs *FunctionStatement) String() string { return fs.Function.format(fs.Name.String()) }
+
 // BlockStatement represents a block statement in the AST
 type BlockStatement struct {
 	Token      token.Token // the { token
 	Statements []Statement
+	EndToken   token.Token // the } token
 }
 
 func (bs *BlockStatement) statementNode() {}
@@ -137,6 +359,20 @@ func (bs *BlockStatement) statementNode() {}
 // TokenLiteral the literal value of the block statement token
 func (bs *BlockStatement) TokenLiteral() string { return bs.Token.Literal }
 
+// Pos the position of the { token
+func (bs *BlockStatement) Pos() token.Position { return bs.Token.Pos }
+
+// End the end position of the } token
+func (bs *BlockStatement) End() token.Position {
+	if bs.EndToken.End.IsValid() {
+		return bs.EndToken.End
+	}
+	if len(bs.Statements) > 0 {
+		return bs.Statements[len(bs.Statements)-1].End()
+	}
+	return bs.Token.End
+}
+
 // String string representation of aa block statement
 func (bs *BlockStatement) String() string {
 	var out bytes.Buffer
@@ -160,6 +396,12 @@ func (i *Identifier) expressionNode() {}
 // TokenLiteral the literal value of the identifier token
 func (i *Identifier) TokenLiteral() string { return i.Token.Literal }
 
+// Pos the position of the identifier token
+func (i *Identifier) Pos() token.Position { return i.Token.Pos }
+
+// End the end position of the identifier token
+func (i *Identifier) End() token.Position { return i.Token.End }
+
 // String string representation of an identifier
 func (i *Identifier) String() string { return i.Value }
 
@@ -174,12 +416,18 @@ func (il *IntegerLiteral) expressionNode() {}
 // TokenLiteral the literal value of the integer token
 func (il *IntegerLiteral) TokenLiteral() string { return il.Token.Literal }
 
+// Pos the position of the integer literal token
+func (il *IntegerLiteral) Pos() token.Position { return il.Token.Pos }
+
+// End the end position of the integer literal token
+func (il *IntegerLiteral) End() token.Position { return il.Token.End }
+
 // String string representation of an integer
 func (il *IntegerLiteral) String() string { return strconv.FormatInt(il.Value, 10) }
 
 // DoubleLiteral represents a double in a statement
 type DoubleLiteral struct {
-	Token     token.Token // the token.INT token
+	Token     token.Token // the token.DOUBLE token
 	Value     float64
 	Precision int // the double's precision
 }
@@ -189,6 +437,12 @@ func (dl *DoubleLiteral) expressionNode() {}
 // TokenLiteral the literal value of the double token
 func (dl *DoubleLiteral) TokenLiteral() string { return dl.Token.Literal }
 
+// Pos the position of the double literal token
+func (dl *DoubleLiteral) Pos() token.Position { return dl.Token.Pos }
+
+// End the end position of the double literal token
+func (dl *DoubleLiteral) End() token.Position { return dl.Token.End }
+
 // String string representation of an double
 func (dl *DoubleLiteral) String() string { return strconv.FormatFloat(dl.Value, 'f', dl.Precision, 64) }
 
@@ -204,6 +458,12 @@ func (pe *PrefixExpression) expressionNode() {}
 // TokenLiteral the literal value of the prefix expression token
 func (pe *PrefixExpression) TokenLiteral() string { return pe.Token.Literal }
 
+// Pos the position of the prefix operator
+func (pe *PrefixExpression) Pos() token.Position { return pe.Token.Pos }
+
+// End the end position of the right operand
+func (pe *PrefixExpression) End() token.Position { return endOf(pe.Right, pe.Token) }
+
 // String string representation of a prefix expression
 func (pe *PrefixExpression) String() string {
 	var out bytes.Buffer
@@ -227,6 +487,12 @@ func (oe *InfixExpression) expressionNode() {}
 // TokenLiteral the literal value of the infix expression token
 func (oe *InfixExpression) TokenLiteral() string { return oe.Token.Literal }
 
+// Pos the start position of the left operand
+func (oe *InfixExpression) Pos() token.Position { return posOf(oe.Left, oe.Token) }
+
+// End the end position of the right operand
+func (oe *InfixExpression) End() token.Position { return endOf(oe.Right, oe.Token) }
+
 // String string representation of a infix expression
 func (oe *InfixExpression) String() string {
 	var out bytes.Buffer
@@ -239,6 +505,73 @@ func (oe *InfixExpression) String() string {
 	return out.String()
 }
 
+// AssignExpression represents an assignment: x = 5 or x += 1
+type AssignExpression struct {
+	Token    token.Token // The assignment operator token, e.g. = or +=
+	Target   Expression
+	Operator string
+	Value    Expression
+}
+
+func (ae *AssignExpression) expressionNode() {}
+
+// TokenLiteral the literal value of the assignment operator token
+func (ae *AssignExpression) TokenLiteral() string { return ae.Token.Literal }
+
+// Pos the start position of the assignment target
+func (ae *AssignExpression) Pos() token.Position { return posOf(ae.Target, ae.Token) }
+
+// End the end position of the assigned value
+func (ae *AssignExpression) End() token.Position { return endOf(ae.Value, ae.Token) }
+
+// String string representation of an assignment
+func (ae *AssignExpression) String() string {
+	var out bytes.Buffer
+	out.WriteString("(")
+	out.WriteString(ae.Target.String())
+	out.WriteString(" " + ae.Operator + " ")
+	out.WriteString(ae.Value.String())
+	out.WriteString(")")
+	return out.String()
+}
+
+// UpdateExpression represents an increment or a decrement: ++x, x++, --x or x--
+type UpdateExpression struct {
+	Token    token.Token // The ++ or -- token
+	Operator string
+	Target   Expression
+	Prefix   bool // whether the operator comes before the target
+}
+
+func (ue *UpdateExpression) expressionNode() {}
+
+// TokenLiteral the literal value of the update operator token
+func (ue *UpdateExpression) TokenLiteral() string { return ue.Token.Literal }
+
+// Pos the position of the prefix operator or of the target
+func (ue *UpdateExpression) Pos() token.Position {
+	if ue.Prefix {
+		return ue.Token.Pos
+	}
+	return posOf(ue.Target, ue.Token)
+}
+
+// End the end position of the target or of the postfix operator
+func (ue *UpdateExpression) End() token.Position {
+	if ue.Prefix {
+		return endOf(ue.Target, ue.Token)
+	}
+	return ue.Token.End
+}
+
+// String string representation of an update expression
+func (ue *UpdateExpression) String() string {
+	if ue.Prefix {
+		return "(" + ue.Operator + ue.Target.String() + ")"
+	}
+	return "(" + ue.Target.String() + ue.Operator + ")"
+}
+
 // IfExpression represents an if expression
 type IfExpression struct {
 	Token       token.Token // The 'if' token
@@ -252,6 +585,20 @@ func (ie *IfExpression) expressionNode() {}
 // TokenLiteral the literal value of the if expression token
 func (ie *IfExpression) TokenLiteral() string { return ie.Token.Literal }
 
+// Pos the position of the if keyword
+func (ie *IfExpression) Pos() token.Position { return ie.Token.Pos }
+
+// End the end position of the last block of the if expression
+func (ie *IfExpression) End() token.Position {
+	if ie.Alternative != nil {
+		return ie.Alternative.End()
+	}
+	if ie.Consequence != nil {
+		return ie.Consequence.End()
+	}
+	return endOf(ie.Condition, ie.Token)
+}
+
 // String string representation of an if expression
 func (ie *IfExpression) String() string {
 	var out bytes.Buffer
@@ -269,6 +616,161 @@ func (ie *IfExpression) String() string {
 	return out.String()
 }
 
+// WhileExpression represents a while loop: while (cond) { ... }
+type WhileExpression struct {
+	Token     token.Token // The 'while' token
+	Condition Expression
+	Body      *BlockStatement
+}
+
+func (we *WhileExpression) expressionNode() {}
+
+// TokenLiteral the literal value of the while expression token
+func (we *WhileExpression) TokenLiteral() string { return we.Token.Literal }
+
+// Pos the position of the while keyword
+func (we *WhileExpression) Pos() token.Position { return we.Token.Pos }
+
+// End the end position of the loop body
+func (we *WhileExpression) End() token.Position {
+	if we.Body != nil {
+		return we.Body.End()
+	}
+	return endOf(we.Condition, we.Token)
+}
+
+// String string representation of a while expression
+func (we *WhileExpression) String() string {
+	var out bytes.Buffer
+	out.WriteString("while (")
+	out.WriteString(we.Condition.String())
+	out.WriteString(") { ")
+	out.WriteString(we.Body.String())
+	out.WriteString(" }")
+	return out.String()
+}
+
+// ForExpression represents a C-style for loop: for (let i = 0; i < n; i += 1) { ... }.
+// Each of the init statement, the condition and the update may be missing
+type ForExpression struct {
+	Token     token.Token // The 'for' token
+	Init      Statement
+	Condition Expression
+	Update    Expression
+	Body      *BlockStatement
+}
+
+func (fe *ForExpression) expressionNode() {}
+
+// TokenLiteral the literal value of the for expression token
+func (fe *ForExpression) TokenLiteral() string { return fe.Token.Literal }
+
+// Pos the position of the for keyword
+func (fe *ForExpression) Pos() token.Position { return fe.Token.Pos }
+
+// End the end position of the loop body
+func (fe *ForExpression) End() token.Position {
+	if fe.Body != nil {
+		return fe.Body.End()
+	}
+	return fe.Token.End
+}
+
+// String string representation of a for expression
+func (fe *ForExpression) String() string {
+	var out bytes.Buffer
+	out.WriteString("for (")
+	if fe.Init != nil {
+		out.WriteString(strings.TrimSuffix(fe.Init.String(), ";"))
+	}
+	out.WriteString("; ")
+	if fe.Condition != nil {
+		out.WriteString(fe.Condition.String())
+	}
+	out.WriteString("; ")
+	if fe.Update != nil {
+		out.WriteString(fe.Update.String())
+	}
+	out.WriteString(") { ")
+	out.WriteString(fe.Body.String())
+	out.WriteString(" }")
+	return out.String()
+}
+
+// ForInExpression represents a loop over the items of an array, a hash or a string:
+// for (x in arr) { ... } or for (k, v in hash) { ... }
+type ForInExpression struct {
+	Token    token.Token // The 'for' token
+	Key      *Identifier // the index or key of the item; nil if a single variable is given
+	Value    *Identifier // the item, or the key of a hash item when Key is nil
+	Iterable Expression
+	Body     *BlockStatement
+}
+
+func (fe *ForInExpression) expressionNode() {}
+
+// TokenLiteral the literal value of the for-in expression token
+func (fe *ForInExpression) TokenLiteral() string { return fe.Token.Literal }
+
+// Pos the position of the for keyword
+func (fe *ForInExpression) Pos() token.Position { return fe.Token.Pos }
+
+// End the end position of the loop body
+func (fe *ForInExpression) End() token.Position {
+	if fe.Body != nil {
+		return fe.Body.End()
+	}
+	return endOf(fe.Iterable, fe.Token)
+}
+
+// String string representation of a for-in expression
+func (fe *ForInExpression) String() string {
+	var out bytes.Buffer
+	out.WriteString("for (")
+	if fe.Key != nil {
+		out.WriteString(fe.Key.String() + ", ")
+	}
+	out.WriteString(fe.Value.String())
+	out.WriteString(" in ")
+	out.WriteString(fe.Iterable.String())
+	out.WriteString(") { ")
+	out.WriteString(fe.Body.String())
+	out.WriteString(" }")
+	return out.String()
+}
+
+// ConditionalExpression represents a ternary conditional: cond ? a : b
+type ConditionalExpression struct {
+	Token       token.Token // The '?' token
+	Condition   Expression
+	Consequence Expression
+	Alternative Expression
+}
+
+func (ce *ConditionalExpression) expressionNode() {}
+
+// TokenLiteral the literal value of the conditional expression token
+func (ce *ConditionalExpression) TokenLiteral() string { return ce.Token.Literal }
+
+// Pos the start position of the condition
+func (ce *ConditionalExpression) Pos() token.Position { return posOf(ce.Condition, ce.Token) }
+
+// End the end position of the alternative
+func (ce *ConditionalExpression) End() token.Position { return endOf(ce.Alternative, ce.Token) }
+
+// String string representation of a conditional expression
+func (ce *ConditionalExpression) String() string {
+	var out bytes.Buffer
+	out.WriteString("(")
+	out.WriteString(ce.Condition.String())
+	out.WriteString(" ? ")
+	out.WriteString(ce.Consequence.String())
+	out.WriteString(" : ")
+	out.WriteString(ce.Alternative.String())
+	out.WriteString(")")
+	return out.String()
+}
+
 // Boolean the boolean struct
 type Boolean struct {
 	Token token.Token
@@ -280,6 +782,12 @@ func (b *Boolean) expressionNode() {}
 // TokenLiteral the literal value of the boolean token
 func (b *Boolean) TokenLiteral() string { return b.Token.Literal }
 
+// Pos the position of the boolean token
+func (b *Boolean) Pos() token.Position { return b.Token.Pos }
+
+// End the end position of the boolean token
+func (b *Boolean) End() token.Position { return b.Token.End }
+
 // String string representation of a boolean
 func (b *Boolean) String() string { return b.Token.Literal }
 
@@ -294,13 +802,51 @@ func (sl *StringLiteral) expressionNode() {}
 // TokenLiteral the literal value of the string token
 func (sl *StringLiteral) TokenLiteral() string { return sl.Token.Literal }
 
+// Pos the position of the string literal token
+func (sl *StringLiteral) Pos() token.Position { return sl.Token.Pos }
+
+// End the end position of the string literal token
+func (sl *StringLiteral) End() token.Position { return sl.Token.End }
+
 // String string representation of a string
 func (sl *StringLiteral) String() string { return sl.Token.Literal }
 
+// InterpolatedString represents a string with embedded expressions: "total: ${a + b} items"
+type InterpolatedString struct {
+	Token    token.Token // the token.STRINGSTART token
+	Parts    Expressions // the string literals and embedded expressions, in order
+	EndToken token.Token // the token.STRINGEND token
+}
+
+func (is *InterpolatedString) expressionNode() {}
+
+// TokenLiteral the literal value of the interpolated string token
+func (is *InterpolatedString) TokenLiteral() string { return is.Token.Literal }
+
+// Pos the position of the opening quote
+func (is *InterpolatedString) Pos() token.Position { return is.Token.Pos }
+
+// End the end position of the closing quote
+func (is *InterpolatedString) End() token.Position { return is.EndToken.End }
+
+// String string representation of an interpolated string
+func (is *InterpolatedString) String() string {
+	var out bytes.Buffer
+	for _, part := range is.Parts {
+		if lit, ok := part.(*StringLiteral); ok {
+			out.WriteString(lit.Value)
+		} else {
+			out.WriteString("${" + part.String() + "}")
+		}
+	}
+	return out.String()
+}
+
 // ArrayLiteral represents a array in a statement
 type ArrayLiteral struct {
 	Token    token.Token // the '[' token
 	Elements Expressions
+	EndToken token.Token // the ']' token
 }
 
 func (al *ArrayLiteral) expressionNode() {}
@@ -308,6 +854,12 @@ func (al *ArrayLiteral) expressionNode() {}
 // TokenLiteral the literal value of the string token
 func (al *ArrayLiteral) TokenLiteral() string { return al.Token.Literal }
 
+// Pos the position of the '[' token
+func (al *ArrayLiteral) Pos() token.Position { return al.Token.Pos }
+
+// End the end position of the ']' token
+func (al *ArrayLiteral) End() token.Position { return al.EndToken.End }
+
 // String string representation of a string
 func (al *ArrayLiteral) String() string {
 	var out bytes.Buffer
@@ -321,10 +873,33 @@ func (al *ArrayLiteral) String() string {
 	return out.String()
 }
 
+// SpreadExpression represents the elements of a value spread into an array literal,
+// the arguments of a call or a hash literal: [...a, 1], f(...args), {...base}
+type SpreadExpression struct {
+	Token token.Token // the '...' token
+	Value Expression
+}
+
+func (se *SpreadExpression) expressionNode() {}
+
+// TokenLiteral the literal value of the spread token
+func (se *SpreadExpression) TokenLiteral() string { return se.Token.Literal }
+
+// Pos the position of the '...' token
+func (se *SpreadExpression) Pos() token.Position { return se.Token.Pos }
+
+// End the end position of the spread value
+func (se *SpreadExpression) End() token.Position { return endOf(se.Value, se.Token) }
+
+// String string representation of a spread expression
+func (se *SpreadExpression) String() string { return se.TokenLiteral() + se.Value.String() }
+
 // HashLiteral represents a hash in a statement
 type HashLiteral struct {
-	Token token.Token // the '{' token
-	Pairs map[Expression]Expression
+	Token    token.Token // the '{' token
+	Pairs    map[Expression]Expression
+	Order    Expressions // the keys of Pairs and the spread expressions, in source order
+	EndToken token.Token // the '}' token
 }
 
 func (hl *HashLiteral) expressionNode() {}
@@ -332,12 +907,22 @@ func (hl *HashLiteral) expressionNode() {}
 // TokenLiteral the literal value of the hash token
 func (hl *HashLiteral) TokenLiteral() string { return hl.Token.Literal }
 
+// Pos the position of the '{' token
+func (hl *HashLiteral) Pos() token.Position { return hl.Token.Pos }
+
+// End the end position of the '}' token
+func (hl *HashLiteral) End() token.Position { return hl.EndToken.End }
+
 // String string representation of a hash
 func (hl *HashLiteral) String() string {
 	var out bytes.Buffer
 	pairs := []string{}
-	for key, value := range hl.Pairs {
-		pairs = append(pairs, key.String()+": "+value.String())
+	for _, key := range hl.Order {
+		if spread, ok := key.(*SpreadExpression); ok {
+			pairs = append(pairs, spread.String())
+		} else {
+			pairs = append(pairs, key.String()+": "+hl.Pairs[key].String())
+		}
 	}
 	out.WriteString("{")
 	out.WriteString(strings.Join(pairs, ", "))
@@ -347,9 +932,10 @@ func (hl *HashLiteral) String() string {
 
 // IndexExpression represents an index in a statement: arr[1]
 type IndexExpression struct {
-	Token token.Token // The [ token
-	Left  Expression
-	Index Expression
+	Token    token.Token // The [ token
+	Left     Expression
+	Index    Expression
+	EndToken token.Token // The ] token
 }
 
 func (ie *IndexExpression) expressionNode() {}
@@ -357,6 +943,12 @@ func (ie *IndexExpression) expressionNode() {}
 // TokenLiteral the literal value of the index expression token
 func (ie *IndexExpression) TokenLiteral() string { return ie.Token.Literal }
 
+// Pos the start position of the indexed expression
+func (ie *IndexExpression) Pos() token.Position { return posOf(ie.Left, ie.Token) }
+
+// End the end position of the ] token
+func (ie *IndexExpression) End() token.Position { return ie.EndToken.End }
+
 // String string representation of a index expression
 func (ie *IndexExpression) String() string {
 	var out bytes.Buffer
@@ -368,10 +960,78 @@ func (ie *IndexExpression) String() string {
 	return out.String()
 }
 
+// SliceExpression represents a slice of an array or a string: arr[1:3], arr[:n] or s[2:]
+type SliceExpression struct {
+	Token    token.Token // The [ token
+	Left     Expression
+	Low      Expression  // the start of the slice; nil if omitted
+	High     Expression  // the end of the slice, excluded; nil if omitted
+	EndToken token.Token // The ] token
+}
+
+func (se *SliceExpression) expressionNode() {}
+
+// TokenLiteral the literal value of the slice expression token
+func (se *SliceExpression) TokenLiteral() string { return se.Token.Literal }
+
+// Pos the start position of the sliced expression
+func (se *SliceExpression) Pos() token.Position { return posOf(se.Left, se.Token) }
+
+// End the end position of the ] token
+func (se *SliceExpression) End() token.Position { return se.EndToken.End }
+
+// String string representation of a slice expression
+func (se *SliceExpression) String() string {
+	var out bytes.Buffer
+	out.WriteString("(")
+	out.WriteString(se.Left.String())
+	out.WriteString("[")
+	if se.Low != nil {
+		out.WriteString(se.Low.String())
+	}
+	out.WriteString(":")
+	if se.High != nil {
+		out.WriteString(se.High.String())
+	}
+	out.WriteString("])")
+	return out.String()
+}
+
+// MemberExpression represents a member access: user.name, the same as user["name"],
+// or arr.push, a method call receiver when called: arr.push(4)
+type MemberExpression struct {
+	Token  token.Token // The . token
+	Object Expression
+	Member *Identifier
+}
+
+func (me *MemberExpression) expressionNode() {}
+
+// TokenLiteral the literal value of the member expression token
+func (me *MemberExpression) TokenLiteral() string { return me.Token.Literal }
+
+// Pos the start position of the accessed object
+func (me *MemberExpression) Pos() token.Position { return posOf(me.Object, me.Token) }
+
+// End the end position of the member name
+func (me *MemberExpression) End() token.Position {
+	if me.Member != nil {
+		return me.Member.End()
+	}
+	return me.Token.End
+}
+
+// String string representation of a member expression
+func (me *MemberExpression) String() string {
+	return me.Object.String() + "." + me.Member.String()
+}
+
 // FunctionLiteral represents a function in a statement
 type FunctionLiteral struct {
 	Token      token.Token // The 'fn' token
 	Parameters Identifiers
+	Defaults   map[string]Expression // the default values of the parameters, by name
+	Rest       *Identifier           // the trailing ...rest parameter; nil if none
 	Body       *BlockStatement
 }
 
@@ -380,29 +1040,57 @@ func (fl *FunctionLiteral) expressionNode() {}
 // TokenLiteral the literal value of the function token
 func (fl *FunctionLiteral) TokenLiteral() string { return fl.Token.Literal }
 
+// Pos the position of the 'fn' token
+func (fl *FunctionLiteral) Pos() token.Position { return fl.Token.Pos }
+
+// End the end position of the function body
+func (fl *FunctionLiteral) End() token.Position {
+	if fl.Body != nil {
+		return fl.Body.End()
+	}
+	return fl.Token.End
+}
+
 // String string representation of a function literal
-func (fl *FunctionLiteral) String() string {
+func (fl *FunctionLiteral) String() string { return fl.format("") + ";" }
+
+// format returns the string representation of the function, declared with the given name
+func (fl *FunctionLiteral) format(name string) string {
 	var out bytes.Buffer
-	params := []string{}
-	for _, p := range fl.Parameters {
-		params = append(params, p.String())
-	}
-	out.WriteString(fl.TokenLiteral())
-	out.WriteString(" (")
-	out.WriteString(strings.Join(params, ", "))
+	out.WriteString(fl.TokenLiteral() + " ")
+	out.WriteString(name)
+	out.WriteString("(")
+	out.WriteString(ParametersString(fl.Parameters, fl.Defaults, fl.Rest))
 	out.WriteString(") { ")
 	for _, s := range fl.Body.Statements {
 		out.WriteString(s.String() + "; ")
 	}
-	out.WriteString("};")
+	out.WriteString("}")
 	return out.String()
 }
 
+// ParametersString returns the parameter list of a function: a, b = 10, ...rest
+func ParametersString(params Identifiers, defaults map[string]Expression, rest *Identifier) string {
+	out := []string{}
+	for _, p := range params {
+		if def, ok := defaults[p.Value]; ok {
+			out = append(out, p.String()+" = "+def.String())
+		} else {
+			out = append(out, p.String())
+		}
+	}
+	if rest != nil {
+		out = append(out, "..."+rest.String())
+	}
+	return strings.Join(out, ", ")
+}
+
 // CallExpression represents a function call expression
 type CallExpression struct {
 	Token     token.Token // The '(' token
 	Function  Expression  // Identifier or FunctionLiteral
 	Arguments Expressions
+	EndToken  token.Token // The ')' token
 }
 
 func (ce *CallExpression) expressionNode() {}
@@ -410,6 +1098,12 @@ func (ce *CallExpression) expressionNode() {}
 // TokenLiteral the literal value of the function call token
 func (ce *CallExpression) TokenLiteral() string { return ce.Token.Literal }
 
+// Pos the start position of the called function
+func (ce *CallExpression) Pos() token.Position { return posOf(ce.Function, ce.Token) }
+
+// End the end position of the ')' token
+func (ce *CallExpression) End() token.Position { return ce.EndToken.End }
+
 // String string representation of a function call literal
 func (ce *CallExpression) String() string {
 	var out bytes.Buffer
@@ -3,6 +3,7 @@ package evaluator
 import (
 	"monkey/object"
 	"strings"
+	"unicode/utf8"
 )
 
 var builtins = map[string]*object.Builtin{
@@ -28,7 +29,7 @@ func _len(args ...object.Object) object.Object {
 
 	switch arg.(type) {
 	case *object.String:
-		return &object.Integer{Value: int64(len(arg.(*object.String).Value))}
+		return &object.Integer{Value: int64(utf8.RuneCountInString(arg.(*object.String).Value))}
 	case *object.Array:
 		return &object.Integer{Value: int64(len(arg.(*object.Array).Elements))}
 	default:
@@ -98,7 +99,10 @@ func _rest(args ...object.Object) object.Object {
 
 	elements := arg.(*object.Array).Elements
 	if length := len(elements); length > 0 {
-		return &object.Array{Elements: elements[1:length]}
+		// copy, as arrays are mutable
+		rest := make(object.Objects, length-1)
+		copy(rest, elements[1:length])
+		return &object.Array{Elements: rest}
 	}
 	return NULL
 }
@@ -122,8 +126,11 @@ func _push(args ...object.Object) object.Object {
 			arg.Type())
 	}
 
+	// push gives a new array, leaving the array it is given untouched
 	elements := arg.(*object.Array).Elements
-	return &object.Array{Elements: append(elements, val)}
+	pushed := make(object.Objects, len(elements), len(elements)+1)
+	copy(pushed, elements)
+	return &object.Array{Elements: append(pushed, val)}
 }
 
 func _puts(args ...object.Object) object.Object {
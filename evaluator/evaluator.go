@@ -1,6 +1,7 @@
 package evaluator
 
 import (
+	"bytes"
 	"fmt"
 	"math"
 	"monkey/ast"
@@ -21,6 +22,12 @@ var (
 
 	// FALSE holds a single false value for reuse
 	FALSE = &object.Boolean{Value: false}
+
+	// BREAK holds a single break value for reuse
+	BREAK = &object.Break{}
+
+	// CONTINUE holds a single continue value for reuse
+	CONTINUE = &object.Continue{}
 )
 
 func newError(format string, a ...interface{}) *object.Error {
@@ -34,8 +41,18 @@ func isError(obj object.Object) bool {
 	return false
 }
 
-// Eval returns the evaluated node as an object
+// Eval returns the evaluated node as an object.
+// Errors are tagged with the position of the innermost node that produced them
 func Eval(node ast.Node, env *object.Environment) object.Object {
+	result := evalNode(node, env)
+	if err, ok := result.(*object.Error); ok && !err.Pos.IsValid() && node != nil {
+		err.Pos = node.Pos()
+	}
+	return result
+}
+
+// evalNode evaluates the node based on its type
+func evalNode(node ast.Node, env *object.Environment) object.Object {
 	switch node := node.(type) {
 	// Statements
 	case *ast.Program:
@@ -63,12 +80,21 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 	case *ast.IfExpression:
 		return evalIfExpression(node, env)
 
+	case *ast.ConditionalExpression:
+		return evalConditionalExpression(node, env)
+
 	case *ast.PrefixExpression:
 		return evalPrefixExpression(node, env)
 
 	case *ast.InfixExpression:
 		return evalInfixExpression(node, env)
 
+	case *ast.AssignExpression:
+		return evalAssignExpression(node, env)
+
+	case *ast.UpdateExpression:
+		return evalUpdateExpression(node, env)
+
 	case *ast.ArrayLiteral:
 		return evalArrayLiteral(node, env)
 
@@ -78,9 +104,34 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 	case *ast.IndexExpression:
 		return evalIndexExpression(node, env)
 
+	case *ast.SliceExpression:
+		return evalSliceExpression(node, env)
+
+	case *ast.MemberExpression:
+		return evalMemberExpression(node, env)
+
 	case *ast.ReturnStatement:
 		return evalReturnStatement(node, env)
 
+	case *ast.FunctionStatement:
+		// bound by hoistFunctions before the statements of its block run
+		return nil
+
+	case *ast.BreakStatement:
+		return BREAK
+
+	case *ast.ContinueStatement:
+		return CONTINUE
+
+	case *ast.WhileExpression:
+		return evalWhileExpression(node, env)
+
+	case *ast.ForExpression:
+		return evalForExpression(node, env)
+
+	case *ast.ForInExpression:
+		return evalForInExpression(node, env)
+
 	case *ast.IntegerLiteral:
 		return &object.Integer{Value: node.Value}
 
@@ -90,6 +141,9 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 	case *ast.StringLiteral:
 		return &object.String{Value: node.Value}
 
+	case *ast.InterpolatedString:
+		return evalInterpolatedString(node, env)
+
 	case *ast.Boolean:
 		return nativeBoolToBooleanObject(node.Value)
 	}
@@ -98,6 +152,7 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 
 func evalProgram(program *ast.Program, env *object.Environment) object.Object {
 	var result object.Object
+	hoistFunctions(program.Statements, env)
 	for _, statement := range program.Statements {
 		result = Eval(statement, env)
 
@@ -115,12 +170,14 @@ func evalProgram(program *ast.Program, env *object.Environment) object.Object {
 
 func evalBlockStatement(block *ast.BlockStatement, env *object.Environment) object.Object {
 	var result object.Object
+	hoistFunctions(block.Statements, env)
 	for _, statement := range block.Statements {
 		result = Eval(statement, env)
 
 		if result != nil {
 			rt := result.Type()
-			if rt == object.RETURNVALUEOBJ || rt == object.ERROROBJ {
+			if rt == object.RETURNVALUEOBJ || rt == object.ERROROBJ ||
+				rt == object.BREAKOBJ || rt == object.CONTINUEOBJ {
 				return result
 			}
 		}
@@ -128,27 +185,105 @@ func evalBlockStatement(block *ast.BlockStatement, env *object.Environment) obje
 	return result
 }
 
+// hoistFunctions binds the functions declared by the statements in env, so that they can
+// be called before their declaration and can call each other
+func hoistFunctions(statements ast.Statements, env *object.Environment) {
+	for _, statement := range statements {
+		if fs, ok := statement.(*ast.FunctionStatement); ok {
+			env.Set(fs.Name.Value, evalFunctionLiteral(fs.Function, env))
+		}
+	}
+}
+
 func evalLetStatement(stmt *ast.LetStatement, env *object.Environment) object.Object {
 	val := Eval(stmt.Value, env)
-
-	// if the eval value is an identifier, then fetch out the value from the identifier
-	if val.Type() == object.IDENTIFIEROBJ {
-		val = val.(*object.Identifier).Value
-	}
 	if isError(val) {
 		return val
 	}
+
+	// if the eval value is an identifier, then fetch out the value from the identifier
+	val = storedValue(val)
+	if stmt.Pattern != nil {
+		return bindPattern(stmt.Pattern, val, env)
+	}
 	env.Set(stmt.Name.Value, val)
 	return nil
 }
 
+// bindPattern binds the identifiers of a destructuring pattern to the parts of the value
+// they match, returning an error if the value doesn't fit the pattern
+func bindPattern(pattern ast.Expression, val object.Object, env *object.Environment) object.Object {
+	val = storedValue(val)
+
+	switch pattern := pattern.(type) {
+	case *ast.Identifier:
+		env.Set(pattern.Value, val)
+
+	case *ast.ArrayPattern:
+		array, ok := val.(*object.Array)
+		if !ok {
+			return newError("cannot destructure %s with an array pattern", val.Type())
+		}
+		for i, elem := range pattern.Elements {
+			var part object.Object
+			if i < len(array.Elements) {
+				part = array.Elements[i]
+			}
+			if err := bindPatternElement(elem, part, env); err != nil {
+				return err
+			}
+		}
+		if pattern.Rest != nil {
+			rest := object.Objects{}
+			if len(array.Elements) > len(pattern.Elements) {
+				rest = append(rest, array.Elements[len(pattern.Elements):]...)
+			}
+			env.Set(pattern.Rest.Value, &object.Array{Elements: rest})
+		}
+
+	case *ast.HashPattern:
+		if val.Type() != object.HASHOBJ {
+			return newError("cannot destructure %s with a hash pattern", val.Type())
+		}
+		for _, elem := range pattern.Elements {
+			var part object.Object
+			if pair, ok := memberPair(val, elem.Key.Value); ok {
+				part = pair.Value
+			}
+			if err := bindPatternElement(elem, part, env); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// bindPatternElement binds the target of a pattern element to its part of the value.
+// A missing or NULL part takes the default value of the element, or NULL without one
+func bindPatternElement(elem *ast.PatternElement, part object.Object, env *object.Environment) object.Object {
+	if part == nil || part == NULL {
+		part = NULL
+		if elem.Default != nil {
+			part = Eval(elem.Default, env)
+			if isError(part) {
+				return part
+			}
+		}
+	}
+	return bindPattern(elem.Target, part, env)
+}
+
 func evalFunctionLiteral(fn *ast.FunctionLiteral, env *object.Environment) object.Object {
 	params := fn.Parameters
 	body := fn.Body
-	return &object.Function{Parameters: params, Env: env, Body: body}
+	return &object.Function{Parameters: params, Defaults: fn.Defaults, Rest: fn.Rest, Env: env, Body: body}
 }
 
 func evalFunctionCall(fn *ast.CallExpression, env *object.Environment) object.Object {
+	if member, ok := fn.Function.(*ast.MemberExpression); ok {
+		return evalMethodCall(member, fn.Arguments, env)
+	}
+
 	function := Eval(fn.Function, env)
 	if isError(function) {
 		return function
@@ -166,24 +301,109 @@ func evalFunctionCall(fn *ast.CallExpression, env *object.Environment) object.Ob
 	return applyFunction(function, args)
 }
 
+// evalMethodCall evaluates a call through a member expression. A function stored in a
+// hash under the member name is called with the arguments, while any other member name
+// must be a builtin, called with the receiver as its first argument: arr.push(4) is push(arr, 4)
+func evalMethodCall(me *ast.MemberExpression, arguments ast.Expressions, env *object.Environment) object.Object {
+	receiver := Eval(me.Object, env)
+	if isError(receiver) {
+		return receiver
+	}
+	receiver = storedValue(receiver)
+
+	// a missing value, NULL, has no methods
+	var function object.Object
+	var args object.Objects
+	if pair, ok := memberPair(receiver, me.Member.Value); ok {
+		function = pair.Value
+	} else if builtin, ok := builtins[me.Member.Value]; ok && receiver != NULL {
+		function = builtin
+		args = object.Objects{receiver}
+	} else {
+		return newError("unknown method: %s.%s", receiver.Type(), me.Member.Value)
+	}
+
+	for _, arg := range evalExpressions(arguments, env) {
+		if isError(arg) {
+			return arg
+		}
+		args = append(args, arg)
+	}
+	return applyFunction(function, args)
+}
+
+// memberPair returns the pair of a hash for the given member name
+func memberPair(obj object.Object, name string) (object.HashPair, bool) {
+	hash, ok := obj.(*object.Hash)
+	if !ok {
+		return object.HashPair{}, false
+	}
+	pair, ok := hash.Pairs[(&object.String{Value: name}).HashKey()]
+	return pair, ok
+}
+
 func evalExpressions(exps ast.Expressions, env *object.Environment) object.Objects {
 	var result object.Objects
 	for _, e := range exps {
+		if spread, ok := e.(*ast.SpreadExpression); ok {
+			elements := evalSpreadElements(spread, env)
+			if len(elements) == 1 && isError(elements[0]) {
+				return elements
+			}
+			result = append(result, elements...)
+			continue
+		}
 		evaluated := Eval(e, env)
 		if isError(evaluated) {
 			return object.Objects{evaluated}
 		}
+		if evaluated == nil {
+			evaluated = NULL
+		}
 		result = append(result, evaluated)
 	}
 	return result
 }
 
+// evalSpreadElements returns the elements of an array, the characters of a string or
+// the keys of a hash, in the order of a for-in loop, spread into an array literal or
+// the arguments of a call
+func evalSpreadElements(spread *ast.SpreadExpression, env *object.Environment) object.Objects {
+	value := Eval(spread.Value, env)
+	if isError(value) {
+		return object.Objects{value}
+	}
+
+	switch value := storedValue(value).(type) {
+	case *object.Array:
+		return value.Elements
+	case *object.String:
+		var elements object.Objects
+		for _, ch := range value.Value {
+			elements = append(elements, &object.String{Value: string(ch)})
+		}
+		return elements
+	case *object.Hash:
+		var keys object.Objects
+		for _, pair := range value.SortedPairs() {
+			keys = append(keys, pair.Key)
+		}
+		return keys
+	default:
+		return object.Objects{newError("cannot spread %s, want %s, %s or %s",
+			value.Type(), object.ARRAYOBJ, object.STRINGOBJ, object.HASHOBJ)}
+	}
+}
+
 func applyFunction(fn object.Object, args object.Objects) object.Object {
 	switch fn := fn.(type) {
 	case *object.Identifier:
 		return applyFunction(fn.Value, args)
 	case *object.Function:
-		extendedEnv := extendFunctionEnv(fn, args)
+		extendedEnv, err := extendFunctionEnv(fn, args)
+		if err != nil {
+			return err
+		}
 		evaluated := Eval(fn.Body, extendedEnv)
 		return unwrapReturnValue(evaluated)
 	case *object.Builtin:
@@ -193,12 +413,60 @@ func applyFunction(fn object.Object, args object.Objects) object.Object {
 	}
 }
 
-func extendFunctionEnv(fn *object.Function, args object.Objects) *object.Environment {
+// extendFunctionEnv binds the arguments of a call to the parameters of the function.
+// A missing argument takes the default value of its parameter, evaluated in the
+// environment of the function, and the arguments left over go to the rest parameter
+func extendFunctionEnv(fn *object.Function, args object.Objects) (*object.Environment, object.Object) {
+	if err := checkArity(fn, len(args)); err != nil {
+		return nil, err
+	}
+
 	env := object.NewEnclosedEnvironment(fn.Env)
 	for paramIdx, param := range fn.Parameters {
-		env.Set(param.Value, args[paramIdx])
+		if paramIdx < len(args) {
+			env.Set(param.Value, storedValue(args[paramIdx]))
+			continue
+		}
+		val := Eval(fn.Defaults[param.Value], fn.Env)
+		if isError(val) {
+			return nil, val
+		}
+		env.Set(param.Value, storedValue(val))
 	}
-	return env
+
+	if fn.Rest != nil {
+		rest := object.Objects{}
+		for paramIdx := len(fn.Parameters); paramIdx < len(args); paramIdx++ {
+			rest = append(rest, storedValue(args[paramIdx]))
+		}
+		env.Set(fn.Rest.Value, &object.Array{Elements: rest})
+	}
+	return env, nil
+}
+
+// checkArity returns an error if the function can't be called with the number of arguments.
+// The parameters up to the last one without a default value need an argument
+func checkArity(fn *object.Function, got int) object.Object {
+	min, max := 0, len(fn.Parameters)
+	for paramIdx, param := range fn.Parameters {
+		if _, ok := fn.Defaults[param.Value]; !ok {
+			min = paramIdx + 1
+		}
+	}
+
+	switch {
+	case fn.Rest != nil:
+		if got < min {
+			return newError("wrong number of arguments. got=%d, want at least %d", got, min)
+		}
+	case min == max:
+		if got != min {
+			return newError("wrong number of arguments. got=%d, want=%d", got, min)
+		}
+	case got < min || got > max:
+		return newError("wrong number of arguments. got=%d, want %d to %d", got, min, max)
+	}
+	return nil
 }
 
 func unwrapReturnValue(obj object.Object) object.Object {
@@ -240,7 +508,26 @@ func evalIfExpression(ie *ast.IfExpression, env *object.Environment) object.Obje
 	}
 }
 
+// evalConditionalExpression evaluates a ternary conditional, evaluating only the
+// selected branch
+func evalConditionalExpression(ce *ast.ConditionalExpression, env *object.Environment) object.Object {
+	condition := Eval(ce.Condition, env)
+
+	// stop propagation here if we encounter an error
+	if isError(condition) {
+		return condition
+	}
+
+	if isTruthy(condition) {
+		return Eval(ce.Consequence, env)
+	}
+	return Eval(ce.Alternative, env)
+}
+
 func isTruthy(obj object.Object) bool {
+	if obj != nil && obj.Type() == object.IDENTIFIEROBJ {
+		obj = obj.(*object.Identifier).Value
+	}
 	switch obj {
 	case NULL:
 		return false
@@ -253,6 +540,114 @@ func isTruthy(obj object.Object) bool {
 	}
 }
 
+// evalWhileExpression evaluates a while loop. Unlike the condition of an if, a condition
+// without a value is false, so that the loop ends
+func evalWhileExpression(we *ast.WhileExpression, env *object.Environment) object.Object {
+	for {
+		condition := Eval(we.Condition, env)
+		if isError(condition) {
+			return condition
+		}
+		if !isTruthy(storedValue(condition)) {
+			return NULL
+		}
+		if result, done := evalLoopBody(we.Body, env); done {
+			return result
+		}
+	}
+}
+
+// evalForExpression evaluates a C-style for loop. Like the statements of its body,
+// the variables declared by its init statement are bound in the enclosing environment
+func evalForExpression(fe *ast.ForExpression, env *object.Environment) object.Object {
+	if fe.Init != nil {
+		if init := Eval(fe.Init, env); isError(init) {
+			return init
+		}
+	}
+	for {
+		if fe.Condition != nil {
+			condition := Eval(fe.Condition, env)
+			if isError(condition) {
+				return condition
+			}
+			if !isTruthy(storedValue(condition)) {
+				return NULL
+			}
+		}
+		if result, done := evalLoopBody(fe.Body, env); done {
+			return result
+		}
+		if fe.Update != nil {
+			if update := Eval(fe.Update, env); isError(update) {
+				return update
+			}
+		}
+	}
+}
+
+// evalForInExpression evaluates a loop over the elements of an array, the runes of a
+// string, or the pairs of a hash in the order of their keys. The second variable, if any,
+// takes the items, while the first one takes their indexes or keys
+func evalForInExpression(fe *ast.ForInExpression, env *object.Environment) object.Object {
+	iterable := Eval(fe.Iterable, env)
+	if isError(iterable) {
+		return iterable
+	}
+
+	var keys, values object.Objects
+	switch iterable := storedValue(iterable).(type) {
+	case *object.Array:
+		values = iterable.Elements
+		for i := range values {
+			keys = append(keys, &object.Integer{Value: int64(i)})
+		}
+	case *object.String:
+		for _, r := range iterable.Value {
+			keys = append(keys, &object.Integer{Value: int64(len(keys))})
+			values = append(values, &object.String{Value: string(r)})
+		}
+	case *object.Hash:
+		for _, pair := range iterable.SortedPairs() {
+			keys = append(keys, pair.Key)
+			values = append(values, pair.Value)
+		}
+		if fe.Key == nil {
+			values = keys
+		}
+	default:
+		return newError("cannot iterate over %s", iterable.Type())
+	}
+
+	for i, value := range values {
+		if fe.Key != nil {
+			env.Set(fe.Key.Value, keys[i])
+		}
+		env.Set(fe.Value.Value, value)
+		if result, done := evalLoopBody(fe.Body, env); done {
+			return result
+		}
+	}
+	return NULL
+}
+
+// evalLoopBody runs one iteration of a loop body and reports whether the loop is done,
+// along with the value the loop evaluates to: NULL after a break, or the return value
+// or error that stopped it
+func evalLoopBody(body *ast.BlockStatement, env *object.Environment) (object.Object, bool) {
+	result := Eval(body, env)
+	if result == nil {
+		return nil, false
+	}
+	switch result.Type() {
+	case object.BREAKOBJ:
+		return NULL, true
+	case object.RETURNVALUEOBJ, object.ERROROBJ:
+		return result, true
+	}
+	return nil, false
+}
+
 func evalPrefixExpression(pref *ast.PrefixExpression, env *object.Environment) object.Object {
 	r := Eval(pref.Right, env)
 	// stop propagation here if we encounter an error
@@ -276,6 +671,11 @@ func evalPrefixExpression(pref *ast.PrefixExpression, env *object.Environment) o
 		default:
 			return newError("unknown operator: %s%s", pref.Operator, r.Type())
 		}
+	case "~":
+		if r.Type() != object.INTEGEROBJ {
+			return newError("unknown operator: %s%s", pref.Operator, r.Type())
+		}
+		return &object.Integer{Value: ^r.(*object.Integer).Value}
 	default:
 		return newError("unknown operator: %s%s", pref.Operator, r.Type())
 	}
@@ -306,6 +706,11 @@ func evalMinusPrefixOperatorExpression(right object.Object) object.Object {
 }
 
 func evalInfixExpression(inf *ast.InfixExpression, env *object.Environment) object.Object {
+	switch inf.Operator {
+	case token.AND, token.OR, token.NULLISH:
+		return evalLogicalExpression(inf, env)
+	}
+
 	left := Eval(inf.Left, env)
 	// stop propagation here if we encounter an error
 	if isError(left) {
@@ -318,29 +723,198 @@ func evalInfixExpression(inf *ast.InfixExpression, env *object.Environment) obje
 		return right
 	}
 
-	modOps := []interface{}{
-		token.PLUSEQ,
-		token.MINUSEQ,
-		token.SLASHEQ,
-		token.ASTERISKEQ,
-	}
+	return evalInfixExpressionByType(inf.Operator, left, right)
+}
 
-	var val object.Object = evalInfixExpressionByType(inf.Operator, left, right)
+// evalAssignExpression evaluates an assignment to a variable, updating the binding in
+// the environment that defines it, or to an array or hash element, updating the array
+// or hash in place. It evaluates to the assigned value
+func evalAssignExpression(ae *ast.AssignExpression, env *object.Environment) object.Object {
+	return evalTargetUpdate(ae.Target, env, func(current object.Object) object.Object {
+		val := Eval(ae.Value, env)
+		if isError(val) {
+			return val
+		}
+		if val == nil {
+			return newError("no value to assign to %s", ae.Target.String())
+		}
+		if val.Type() == object.IDENTIFIEROBJ {
+			val = val.(*object.Identifier).Value
+		}
+		if ae.Operator == token.ASSIGN {
+			return val
+		}
+		if current == nil {
+			return newError("identifier not found: " + ae.Target.String())
+		}
+		return evalInfixExpressionByType(ae.Operator, current, val)
+	})
+}
 
-	if utils.InArray(inf.Operator, modOps) {
-		if val.Type() == object.ERROROBJ {
+// evalUpdateExpression evaluates an increment or a decrement of an integer or a double.
+// The prefix form evaluates to the new value, the postfix form to the old one
+func evalUpdateExpression(ue *ast.UpdateExpression, env *object.Environment) object.Object {
+	var old object.Object
+	val := evalTargetUpdate(ue.Target, env, func(current object.Object) object.Object {
+		if current == nil {
+			return newError("identifier not found: " + ue.Target.String())
+		}
+		old = current
+		delta := int64(1)
+		if ue.Operator == token.DECREMENT {
+			delta = -1
+		}
+		switch current := current.(type) {
+		case *object.Integer:
+			return &object.Integer{Value: current.Value + delta}
+		case *object.Double:
+			return &object.Double{Value: current.Value + float64(delta), Precision: current.Precision}
+		}
+		if ue.Prefix {
+			return newError("unknown operator: %s%s", ue.Operator, current.Type())
+		}
+		return newError("unknown operator: %s%s", current.Type(), ue.Operator)
+	})
+	if isError(val) || ue.Prefix {
+		return val
+	}
+	return old
+}
+
+// evalTargetUpdate replaces the value of a variable, or of an array or hash element,
+// by the one computed by update from its current value. The current value is nil
+// for an undefined variable, and NULL for a missing hash key.
+// It returns the new value, or an error
+func evalTargetUpdate(target ast.Expression, env *object.Environment,
+	update func(current object.Object) object.Object) object.Object {
+	switch target := target.(type) {
+	case *ast.Identifier:
+		current, _ := env.Get(target.Value)
+		if current != nil && current.Type() == object.IDENTIFIEROBJ {
+			current = current.(*object.Identifier).Value
+		}
+		val := update(current)
+		if isError(val) {
 			return val
 		}
+		if _, ok := env.Assign(target.Value, val); !ok {
+			return newError("assignment to undeclared identifier: " + target.Value)
+		}
+		return val
+	case *ast.IndexExpression:
+		return evalIndexUpdate(target, env, update)
+	case *ast.MemberExpression:
+		return evalIndexUpdate(memberIndex(target), env, update)
+	default:
+		return newError("cannot assign to %s", target.String())
+	}
+}
+
+// storedValue returns the value held by an array element or a hash pair, which is NULL
+// for the missing value of an empty block
+func storedValue(obj object.Object) object.Object {
+	if obj == nil {
+		return NULL
+	}
+	if obj.Type() == object.IDENTIFIEROBJ {
+		return obj.(*object.Identifier).Value
+	}
+	return obj
+}
+
+func evalIndexUpdate(ie *ast.IndexExpression, env *object.Environment,
+	update func(current object.Object) object.Object) object.Object {
+	left := Eval(ie.Left, env)
+	if isError(left) {
+		return left
+	}
+	index := Eval(ie.Index, env)
+	if isError(index) {
+		return index
+	}
+	index = storedValue(index)
 
-		switch inf.Left.(type) {
-		case *ast.Identifier:
-			env.Set(inf.Left.String(), val)
+	switch left := storedValue(left).(type) {
+	case *object.Array:
+		i, ok := index.(*object.Integer)
+		if !ok {
+			return newError("array index must be %s, got %s", object.INTEGEROBJ, index.Type())
+		}
+		length := len(left.Elements)
+		idx, ok := elementIndex(i.Value, length)
+		if !ok {
+			return newError("array index out of bounds[%d, %d]: %d", -length, length-1, i.Value)
+		}
+		val := update(storedValue(left.Elements[idx]))
+		if isError(val) {
+			return val
+		}
+		left.Elements[idx] = val
+		return val
+	case *object.Hash:
+		key, ok := index.(object.Hashable)
+		if !ok {
+			return newError("unusable as hash key: %s", index.Type())
+		}
+		hashed := key.HashKey()
+		var current object.Object
+		if pair, ok := left.Pairs[hashed]; ok {
+			current = pair.Value
 		}
+		val := update(storedValue(current))
+		if isError(val) {
+			return val
+		}
+		left.Pairs[hashed] = object.HashPair{Key: index, Value: val}
+		return val
+	default:
+		return newError("index assignment not supported: %s", left.Type())
+	}
+}
 
-		return nil
+// evalLogicalExpression evaluates the short-circuit operators, evaluating the right
+// operand only when the left one doesn't decide the result: && and || give a boolean
+// following isTruthy, while ?? gives the left operand unless it is null
+func evalLogicalExpression(inf *ast.InfixExpression, env *object.Environment) object.Object {
+	left := Eval(inf.Left, env)
+	// stop propagation here if we encounter an error
+	if isError(left) {
+		return left
+	}
+	if left != nil && left.Type() == object.IDENTIFIEROBJ {
+		left = left.(*object.Identifier).Value
+	}
+
+	switch inf.Operator {
+	case token.AND:
+		if !isTruthy(left) {
+			return FALSE
+		}
+	case token.OR:
+		if isTruthy(left) {
+			return TRUE
+		}
+	case token.NULLISH:
+		if left != nil && left != NULL {
+			return left
+		}
 	}
 
-	return val
+	right := Eval(inf.Right, env)
+	// stop propagation here if we encounter an error
+	if isError(right) {
+		return right
+	}
+	if right != nil && right.Type() == object.IDENTIFIEROBJ {
+		right = right.(*object.Identifier).Value
+	}
+	if inf.Operator == token.NULLISH {
+		if right == nil {
+			return NULL
+		}
+		return right
+	}
+	return nativeBoolToBooleanObject(isTruthy(right))
 }
 
 func evalInfixExpressionByType(operator string, left object.Object, right object.Object) object.Object {
@@ -412,6 +986,25 @@ func evalInfixExpressionByType(operator string, left object.Object, right object
 	}
 }
 
+func evalInterpolatedString(is *ast.InterpolatedString, env *object.Environment) object.Object {
+	var out bytes.Buffer
+	for _, part := range is.Parts {
+		if lit, ok := part.(*ast.StringLiteral); ok {
+			out.WriteString(lit.Value)
+			continue
+		}
+		value := Eval(part, env)
+		if isError(value) {
+			return value
+		}
+		if value == nil {
+			value = NULL
+		}
+		out.WriteString(value.Inspect())
+	}
+	return &object.String{Value: out.String()}
+}
+
 func evalArrayLiteral(al *ast.ArrayLiteral, env *object.Environment) object.Object {
 	elements := evalExpressions(al.Elements, env)
 
@@ -427,22 +1020,39 @@ func evalArrayLiteral(al *ast.ArrayLiteral, env *object.Environment) object.Obje
 
 func evalHashLiteral(node *ast.HashLiteral, env *object.Environment) object.Object {
 	pairs := make(map[object.HashKey]object.HashPair)
-	for keyNode, valueNode := range node.Pairs {
+	for _, keyNode := range node.Order {
+		if spread, ok := keyNode.(*ast.SpreadExpression); ok {
+			value := Eval(spread.Value, env)
+			if isError(value) {
+				return value
+			}
+			value = storedValue(value)
+			base, ok := value.(*object.Hash)
+			if !ok {
+				return newError("cannot spread %s into a hash, want %s", value.Type(), object.HASHOBJ)
+			}
+			for hashed, pair := range base.Pairs {
+				pairs[hashed] = pair
+			}
+			continue
+		}
+
 		key := Eval(keyNode, env)
 		if isError(key) {
 			return key
 		}
-		if key.Type() == object.IDENTIFIEROBJ {
-			key = key.(*object.Identifier).Value
-		}
+		key = storedValue(key)
 		hashKey, ok := key.(object.Hashable)
 		if !ok {
 			return newError("unusable as hash key: %s", key.Type())
 		}
-		value := Eval(valueNode, env)
+		value := Eval(node.Pairs[keyNode], env)
 		if isError(value) {
 			return value
 		}
+		if value == nil {
+			value = NULL
+		}
 		hashed := hashKey.HashKey()
 		pairs[hashed] = object.HashPair{Key: key, Value: value}
 	}
@@ -459,15 +1069,13 @@ func evalIndexExpression(ie *ast.IndexExpression, env *object.Environment) objec
 		return index
 	}
 
-	if left.Type() == object.IDENTIFIEROBJ {
-		left = left.(*object.Identifier).Value
-	}
-	if index.Type() == object.IDENTIFIEROBJ {
-		index = index.(*object.Identifier).Value
-	}
+	left = storedValue(left)
+	index = storedValue(index)
 	switch {
 	case left.Type() == object.ARRAYOBJ && index.Type() == object.INTEGEROBJ:
 		return evalArrayIndexExpression(left, index)
+	case left.Type() == object.STRINGOBJ && index.Type() == object.INTEGEROBJ:
+		return evalStringIndexExpression(left, index)
 	case left.Type() == object.HASHOBJ:
 		return evalHashIndexExpression(left, index)
 	default:
@@ -475,14 +1083,126 @@ func evalIndexExpression(ie *ast.IndexExpression, env *object.Environment) objec
 	}
 }
 
+func evalMemberExpression(me *ast.MemberExpression, env *object.Environment) object.Object {
+	left := Eval(me.Object, env)
+	if isError(left) {
+		return left
+	}
+	left = storedValue(left)
+	if left.Type() != object.HASHOBJ {
+		return newError("member access not supported: %s.%s", left.Type(), me.Member.Value)
+	}
+	if pair, ok := memberPair(left, me.Member.Value); ok {
+		return pair.Value
+	}
+	return NULL
+}
+
+// memberIndex returns the index expression that a member expression is sugar for:
+// user["name"] for user.name
+func memberIndex(me *ast.MemberExpression) *ast.IndexExpression {
+	key := &ast.StringLiteral{Token: me.Member.Token, Value: me.Member.Value}
+	return &ast.IndexExpression{Token: me.Token, Left: me.Object, Index: key, EndToken: me.Member.Token}
+}
+
+// elementIndex returns the position of the element at idx in a sequence of the given
+// length, counting from the end if idx is negative, and whether it is within bounds
+func elementIndex(idx int64, length int) (int64, bool) {
+	if idx < 0 {
+		idx += int64(length)
+	}
+	return idx, idx >= 0 && idx < int64(length)
+}
+
 func evalArrayIndexExpression(array, index object.Object) object.Object {
 	arrayObject := array.(*object.Array)
 	idx := index.(*object.Integer).Value
-	max := int64(len(arrayObject.Elements) - 1)
-	if idx < 0 || idx > max {
-		return newError("array index out of bounds[0, %d]: %d", max, idx)
+	length := len(arrayObject.Elements)
+	i, ok := elementIndex(idx, length)
+	if !ok {
+		return newError("array index out of bounds[%d, %d]: %d", -length, length-1, idx)
+	}
+	return arrayObject.Elements[i]
+}
+
+// evalStringIndexExpression returns the rune at the index of the string, as a string
+func evalStringIndexExpression(str, index object.Object) object.Object {
+	runes := []rune(str.(*object.String).Value)
+	idx := index.(*object.Integer).Value
+	i, ok := elementIndex(idx, len(runes))
+	if !ok {
+		return newError("string index out of bounds[%d, %d]: %d", -len(runes), len(runes)-1, idx)
+	}
+	return &object.String{Value: string(runes[i])}
+}
+
+// evalSliceExpression returns a new array holding the elements of an array between the
+// bounds of the slice, or the string made of the runes of a string between them
+func evalSliceExpression(se *ast.SliceExpression, env *object.Environment) object.Object {
+	left := Eval(se.Left, env)
+	if isError(left) {
+		return left
+	}
+	left = storedValue(left)
+
+	var length int
+	var runes []rune
+	switch left := left.(type) {
+	case *object.Array:
+		length = len(left.Elements)
+	case *object.String:
+		runes = []rune(left.Value)
+		length = len(runes)
+	default:
+		return newError("slice operator not supported: %s", left.Type())
+	}
+
+	low, err := evalSliceBound(se.Low, 0, length, env)
+	if err != nil {
+		return err
 	}
-	return arrayObject.Elements[idx]
+	high, err := evalSliceBound(se.High, length, length, env)
+	if err != nil {
+		return err
+	}
+	if high < low {
+		high = low
+	}
+
+	if array, ok := left.(*object.Array); ok {
+		elements := make(object.Objects, high-low)
+		copy(elements, array.Elements[low:high])
+		return &object.Array{Elements: elements}
+	}
+	return &object.String{Value: string(runes[low:high])}
+}
+
+// evalSliceBound evaluates a bound of a slice of a sequence of the given length, counting
+// from the end if it is negative and clamping it to the sequence. A missing bound is def
+func evalSliceBound(bound ast.Expression, def int, length int, env *object.Environment) (int, object.Object) {
+	if bound == nil {
+		return def, nil
+	}
+	obj := Eval(bound, env)
+	if isError(obj) {
+		return 0, obj
+	}
+	obj = storedValue(obj)
+	integer, ok := obj.(*object.Integer)
+	if !ok {
+		return 0, newError("slice index must be %s, got %s", object.INTEGEROBJ, obj.Type())
+	}
+	idx := integer.Value
+	if idx < 0 {
+		idx += int64(length)
+	}
+	if idx < 0 {
+		return 0, nil
+	}
+	if idx > int64(length) {
+		return length, nil
+	}
+	return int(idx), nil
 }
 
 func evalHashIndexExpression(hash, index object.Object) object.Object {
@@ -549,11 +1269,31 @@ func evalIntegerInfixExpression(operator string, left object.Object, right objec
 		val := int64(math.Mod(float64(lvalue), float64(rvalue)))
 		return &object.Integer{Value: val}
 
+	// & | xor << >>
+	case token.BITAND:
+		return &object.Integer{Value: lvalue & rvalue}
+	case token.BITOR:
+		return &object.Integer{Value: lvalue | rvalue}
+	case token.XOR:
+		return &object.Integer{Value: lvalue ^ rvalue}
+	case token.SHL, token.SHR:
+		return evalShiftOperatorIntegerExpression(operator, lvalue, rvalue)
+
 	default:
 		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
 	}
 }
 
+func evalShiftOperatorIntegerExpression(operator string, lvalue int64, rvalue int64) object.Object {
+	if rvalue < 0 {
+		return newError("negative shift count: %d", rvalue)
+	}
+	if operator == token.SHL {
+		return &object.Integer{Value: lvalue << uint64(rvalue)}
+	}
+	return &object.Integer{Value: lvalue >> uint64(rvalue)}
+}
+
 func evalDoubleInfixExpression(operator string, left object.Object, right object.Object) object.Object {
 
 	l := left.(*object.Double)
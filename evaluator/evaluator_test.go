@@ -110,6 +110,14 @@ func TestEvalBooleanExpression(t *testing.T) {
 		{"2 % 4.0 * 5^2 - 2 / 4 == 50", true},
 		{"2.0 % 4 * 5^2 - 2 / 4 == 50", true},
 		{"2.0 % 4.0 * 5.0^2.0 - 2.0 / 4.0 == 49.5", true},
+
+		{"1.05 == 1.05", true},
+		{"1.05 == 1.5", false},
+		{".5 + .5 == 1.0", true},
+		{"1e3 == 1000.0", true},
+		{"0xff == 255", true},
+		{"0o17 + 0b1 == 16", true},
+		{"1_000 == 1000", true},
 	}
 	for _, tt := range tests {
 		evaluated := testEval(tt.input)
@@ -160,6 +168,372 @@ func TestIfElseExpressions(t *testing.T) {
 	}
 }
 
+func TestLoops(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let i = 0; while (i < 5) { i += 1 }; i", 5},
+		{"let i = 10; while (i < 5) { i += 1 }; i", 10},
+		{"let i = 0; while (true) { i += 1; if (i == 3) { break } }; i", 3},
+		{"let i = 0; let odd = 0; while (i < 10) { i += 1; if (i % 2 == 0) { continue } odd += 1 }; odd", 5},
+		{"let sum = 0; for (let i = 1; i <= 10; i += 1) { sum += i }; sum", 55},
+		{"let sum = 0; for (let i = 0; i < 10; i += 1) { if (i == 5) { break } sum += i }; sum", 10},
+		{"let sum = 0; for (let i = 0; i < 10; i += 1) { if (i % 2 == 1) { continue } sum += i }; sum", 20},
+		{"let sum = 0; for (let i = 0; i < 3; i += 1) { for (let j = 0; j < 3; j += 1) { if (j == 1) { break } sum += 1 } }; sum", 3},
+		{"let n = 0; for (;;) { n += 1; if (n == 4) { break } }; n", 4},
+		{"let find = fn(x) { for (let i = 0; i < 10; i += 1) { if (i * i == x) { return i } } }; find(49)", 7},
+		{"let running = false; let n = 1; while (running) { n += 1 }; n", 1},
+		{"while (false) { 1 }", nil},
+		{"let f = fn() {}; let n = 0; while (f()) { n += 1; if (n == 3) { break } }; n", 0},
+		{"let f = fn() {}; let n = 0; for (; f(); n += 1) { if (n == 3) { break } }; n", 0},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		integer, ok := tt.expected.(int)
+		if ok {
+			testIntegerObject(t, evaluated, int64(integer))
+		} else {
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestForInLoops(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let sum = 0; for (x in [1, 2, 3]) { sum += x }; sum", 6},
+		{"let sum = 0; for (i, x in [10, 20, 30]) { sum += i * x }; sum", 80},
+		{"let sum = 0; for (x in []) { sum += 1 }; sum", 0},
+		{"let sum = 0; for (x in [1, 2, 3, 4]) { if (x == 2) { continue } if (x == 4) { break } sum += x }; sum", 4},
+		{`let s = ""; for (k in {"b": 2, "a": 1, "c": 3}) { s += k }; s`, "abc"},
+		{`let s = ""; for (k, v in {"b": 2, "a": 1}) { s += "${k}=${v};" }; s`, "a=1;b=2;"},
+		{`let s = ""; for (ch in "héllo") { s += ch + "." }; s`, "h.é.l.l.o."},
+		{`let n = 0; for (i, ch in "日本語") { n += i }; n`, 3},
+		{"for (x in 5) { x }", "cannot iterate over INTEGER"},
+		{"let f = fn() { let y = 1 }; for (x in f()) {}", "cannot iterate over NULL"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			switch obj := evaluated.(type) {
+			case *object.String:
+				if obj.Value != expected {
+					t.Errorf("String has wrong value. expected=%q, got=%q", expected, obj.Value)
+				}
+			case *object.Error:
+				if obj.Message != expected {
+					t.Errorf("wrong error message. expected=%q, got=%q", expected, obj.Message)
+				}
+			default:
+				t.Errorf("object is not String or Error. got=%T (%+v)", evaluated, evaluated)
+			}
+		}
+	}
+}
+
+func TestLogicalOperators(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"true && true", true},
+		{"true && false", false},
+		{"false || true", true},
+		{"false || false", false},
+		{"1 && 0", true},
+		{"let x = null_value ?? 5; x", "identifier not found: null_value"},
+		{"if (false) { 1 } ?? 5", 5},
+		{"let h = {}; h[\"a\"] ?? 7", 7},
+		{"let h = {\"a\": false}; h[\"a\"] ?? 7", false},
+		{"let x = 3; x ?? 7", 3},
+		{"false && missing()", false},
+		{"true || missing()", true},
+		{"1 ?? missing()", 1},
+		{"true && missing()", "identifier not found: missing"},
+		{"false || missing()", "identifier not found: missing"},
+		{"false && (1 + true)", false},
+		{"true && (1 + true)", "type mismatch: INTEGER + BOOLEAN"},
+		{"let x = 5; x > 1 && x < 10", true},
+		{"let ok = false; ok || !ok", true},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("no error object returned for %q. got=%T(%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestBitwiseOperators(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"0b1100 & 0b1010", 0b1000},
+		{"0b1100 | 0b1010", 0b1110},
+		{"0b1100 xor 0b1010", 0b0110},
+		{"~0", -1},
+		{"~5", -6},
+		{"1 << 10", 1024},
+		{"1024 >> 3", 128},
+		{"-16 >> 2", -4},
+		{"1 << 64", 0},
+		{"let flags = 0x35; (flags >> 4) & 0x3", 3},
+		{"let flags = 0x35; flags & ~0x1", 0x34},
+		{"1 << -1", "negative shift count: -1"},
+		{"8 >> -2", "negative shift count: -2"},
+		{"1.5 & 1.0", "unknown operator: DOUBLE & DOUBLE"},
+		{"true | false", "unknown operator: BOOLEAN | BOOLEAN"},
+		{"1 xor true", "type mismatch: INTEGER xor BOOLEAN"},
+		{"~true", "unknown operator: ~BOOLEAN"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("no error object returned for %q. got=%T(%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestAssignExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let x = 1; x = 5; x", 5},
+		{"let x = 1; x = x + 1; x", 2},
+		{"let x = 1; x = 7", 7},
+		{"let a = 1; let b = 2; a = b = 3; a + b", 6},
+		{"let count = 0; let inc = fn() { count += 1 }; inc(); inc(); count", 2},
+		{"let count = 0; let set = fn(n) { count = n }; set(9); count", 9},
+		{"let x = 1; let f = fn() { let x = 2; x = 3; x }; f() * 10 + x", 31},
+		{"let makeCounter = fn() { let n = 0; fn() { n += 1 } }; let c = makeCounter(); c(); c(); c()", 3},
+		{"let i = 0; for (i = 5; i < 8; i += 1) { }; i", 8},
+		{"y = 1", "assignment to undeclared identifier: y"},
+		{"let f = fn() { z = 1 }; f()", "assignment to undeclared identifier: z"},
+		{"z += 1", "identifier not found: z"},
+		{"let x = 1; x = 1 + true", "type mismatch: INTEGER + BOOLEAN"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("no error object returned for %q. got=%T(%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestIndexAssignExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let a = [1, 2, 3]; a[1] = 5; a[1]", 5},
+		{"let a = [1, 2, 3]; a[0] += 10; a[0] + a[2]", 14},
+		{"let a = [1, 2, 3]; a[2] = 9", 9},
+		{"let a = [[1, 2], [3, 4]]; a[1][0] *= 5; a[1][0]", 15},
+		{"let a = [1, 2]; let b = a; b[0] = 7; a[0]", 7},
+		{"let a = [1, 2]; let set = fn(arr) { arr[1] = 8 }; set(a); a[1]", 8},
+		{"let a = [1, 2]; let b = push(a, 3); b[0] = 4; a[0]", 1},
+		{"let a = [1, 2, 3]; let b = rest(a); b[0] = 4; a[1]", 2},
+		{"let a = [0, 0, 0]; for (i, x in a) { a[i] = i * 2 }; a[2]", 4},
+		{`let h = {"count": 1}; h["count"] += 1; h["count"]`, 2},
+		{`let h = {}; h["new"] = 3; h["new"]`, 3},
+		{`let h = {}; h[1] = 2; h[true] = 3; h[1] + h[true]`, 5},
+		{`let h = {"a": [1]}; h["a"][0] = 6; h["a"][0]`, 6},
+		{"let f = fn() {}; let a = [f(), 2]; a[0] = 1; a[0]", 1},
+		{`let f = fn() {}; let h = {"a": f()}; h["a"] = 1; h["a"]`, 1},
+		{`let f = fn() {}; let h = {"a": f()}; h["a"] += 1`, "type mismatch: NULL += INTEGER"},
+		{"let f = fn() {}; let a = [f()]; a[0]++", "unknown operator: NULL++"},
+		{"let f = fn() {}; f()[0] = 1", "index assignment not supported: NULL"},
+		{"let f = fn() {}; let a = [1]; a[f()] = 1", "array index must be INTEGER, got NULL"},
+		{"let f = fn() {}; let h = {}; h[f()] = 1", "unusable as hash key: NULL"},
+		{"let f = fn() {}; f().x = 1", "index assignment not supported: NULL"},
+		{"let a = [1, 2, 3]; a[3] = 4", "array index out of bounds[-3, 2]: 3"},
+		{"let a = [1, 2, 3]; a[-1] = 4; a[2]", 4},
+		{"let a = [1, 2, 3]; a[-4] = 4", "array index out of bounds[-3, 2]: -4"},
+		{`let a = [1]; a["0"] = 4`, "array index must be INTEGER, got STRING"},
+		{`let h = {}; h[fn(x) { x }] = 1`, "unusable as hash key: FUNCTION"},
+		{`let h = {}; h["missing"] += 1`, "type mismatch: NULL += INTEGER"},
+		{`let s = 5; s[0] = 1`, "index assignment not supported: INTEGER"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("no error object returned for %q. got=%T(%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestUpdateExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let i = 1; ++i", 2},
+		{"let i = 1; i++", 1},
+		{"let i = 1; i++; i", 2},
+		{"let i = 1; --i", 0},
+		{"let i = 1; i--", 1},
+		{"let i = 1; i--; i--; i", -1},
+		{"let i = 5; let j = i++ + ++i; j * 10 + i", 127},
+		{"let x = 1.5; x++; x", 2.5},
+		{"let x = 1.5; --x", 0.5},
+		{"let a = [1, 2]; a[0]++; ++a[1]; a[0] * 10 + a[1]", 23},
+		{"let a = [1, 2]; a[1]--", 2},
+		{`let h = {"n": 1}; h["n"]++; h["n"]`, 2},
+		{`let h = {"n": 1}; ++h["n"]`, 2},
+		{"let n = 0; let inc = fn() { n++ }; inc(); inc(); n", 2},
+		{"let f = fn(x) { x++; x }; f(4)", 5},
+		{"let sum = 0; for (let i = 0; i < 4; i++) { sum += i }; sum", 6},
+		{"i++", "identifier not found: i"},
+		{`let s = "a"; s++`, "unknown operator: STRING++"},
+		{`let s = "a"; --s`, "unknown operator: --STRING"},
+		{`let h = {}; h["n"]++`, "unknown operator: NULL++"},
+		{"let a = [1]; a[1]++", "array index out of bounds[-1, 0]: 1"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case float64:
+			double, ok := evaluated.(*object.Double)
+			if !ok {
+				t.Errorf("object is not Double. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if double.Value != expected {
+				t.Errorf("object has wrong value. got=%v, want=%v", double.Value, expected)
+			}
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("no error object returned for %q. got=%T(%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestConditionalExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"true ? 1 : 2", 1},
+		{"false ? 1 : 2", 2},
+		{"if (false) { 1 } ? 1 : 2", 2},
+		{"let x = 5; x > 3 ? x * 2 : x", 10},
+		{"let n = 0; n == 0 ? 1 : n < 0 ? 2 : 3", 1},
+		{"let n = -4; n == 0 ? 1 : n < 0 ? 2 : 3", 2},
+		{"let n = 4; n == 0 ? 1 : n < 0 ? 2 : 3", 3},
+		{"true ? 1 : missing()", 1},
+		{"false ? missing() : 2", 2},
+		{"let i = 0; true ? i++ : i--; i", 1},
+		{"let fact = fn(n) { n <= 1 ? 1 : n * fact(n - 1) }; fact(5)", 120},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testIntegerObject(t, evaluated, tt.expected)
+	}
+}
+
+func TestMemberExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`let user = {"age": 30}; user.age`, 30},
+		{`let user = {"address": {"zip": 1000}}; user.address.zip`, 1000},
+		{`let user = {}; user.age`, nil},
+		{`let user = {"age": 30}; user.age = 31; user["age"]`, 31},
+		{`let user = {"age": 30}; user.age += 2; user.age++; user.age`, 33},
+		{`let user = {}; user.name = "x"; len(user.name)`, 1},
+		{`let counter = {"inc": fn(x) { x + 1 }}; counter.inc(1)`, 2},
+		{`[1, 2, 3].len()`, 3},
+		{`"hello".len()`, 5},
+		{`let arr = [1, 2]; arr.push(3).len()`, 3},
+		{`let arr = [1, 2]; arr.push(3).last()`, 3},
+		{`let arr = [4, 5]; arr.rest().first()`, 5},
+		{`let user = {"len": fn() { 42 }}; user.len()`, 42},
+		{`let arr = [1]; arr.size`, "member access not supported: ARRAY.size"},
+		{`let arr = [1]; arr.size()`, "unknown method: ARRAY.size"},
+		{`5.len()`, "argument to `len` not supported, got INTEGER, want STRING or ARRAY"},
+		{`let user = {}; user.missing()`, "unknown method: HASH.missing"},
+		{"let f = fn() {}; f().len()", "unknown method: NULL.len"},
+		{"let f = fn() {}; f().x", "member access not supported: NULL.x"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("no error object returned for %q. got=%T(%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		default:
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
 func TestReturnStatements(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -240,11 +614,39 @@ func TestErrorHandling(t *testing.T) {
 
 		{
 			"[1, 2, 3][3]",
-			"array index out of bounds[0, 2]: 3",
+			"array index out of bounds[-3, 2]: 3",
 		},
 		{
-			"[1, 2, 3][-1]",
-			"array index out of bounds[0, 2]: -1",
+			"[1, 2, 3][-4]",
+			"array index out of bounds[-3, 2]: -4",
+		},
+		{
+			`"abc"[3]`,
+			"string index out of bounds[-3, 2]: 3",
+		},
+		{
+			`[1, 2, 3]["a":]`,
+			"slice index must be INTEGER, got STRING",
+		},
+		{
+			"5[1:2]",
+			"slice operator not supported: INTEGER",
+		},
+		{
+			"let f = fn() {}; f()[0]",
+			"index operator not supported: NULL",
+		},
+		{
+			"let f = fn() {}; [1][f()]",
+			"index operator not supported: ARRAY",
+		},
+		{
+			"let f = fn() {}; f()[1:2]",
+			"slice operator not supported: NULL",
+		},
+		{
+			"let f = fn() {}; [1, 2][f():]",
+			"slice index must be INTEGER, got NULL",
 		},
 
 		{
@@ -332,6 +734,197 @@ func TestFunctionApplication(t *testing.T) {
 	}
 }
 
+func TestFunctionArguments(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let add = fn(a, b = 10) { a + b }; add(1)", 11},
+		{"let add = fn(a, b = 10) { a + b }; add(1, 2)", 3},
+		{"let x = 1; let f = fn(a = x * 2) { a }; x = 5; f()", 10},
+		{"let f = fn(a = b) { a }; let b = 7; f()", 7},
+		{"let calls = 0; let f = fn(a = calls += 1) { a }; let x = f(); let y = f(); let z = f(5); calls", 2},
+		{"let f = fn(a, ...rest) { len(rest) }; f(1, 2, 3)", 2},
+		{"let f = fn(a, ...rest) { len(rest) }; f(1)", 0},
+		{"let f = fn(...rest) { rest[0] + rest[-1] }; f(1, 2, 3)", 4},
+		{"fn sum(first = 0, ...rest) { for (x in rest) { first += x }; first }; sum(1, 2, 3) + sum()", 6},
+		{"let add = fn(a, b) { a + b }; add(1)", "wrong number of arguments. got=1, want=2"},
+		{"let add = fn(a, b) { a + b }; add(1, 2, 3)", "wrong number of arguments. got=3, want=2"},
+		{"let f = fn() { 1 }; f(1)", "wrong number of arguments. got=1, want=0"},
+		{"let f = fn(a, b = 1) { a }; f()", "wrong number of arguments. got=0, want 1 to 2"},
+		{"let f = fn(a, b = 1) { a }; f(1, 2, 3)", "wrong number of arguments. got=3, want 1 to 2"},
+		{"let f = fn(a = 1, b) { a }; f(1)", "wrong number of arguments. got=1, want=2"},
+		{"let f = fn(a, b, ...rest) { a }; f(1)", "wrong number of arguments. got=1, want at least 2"},
+		{"let f = fn(a = missing) { a }; f()", "identifier not found: missing"},
+		{"let f = fn() {}; let g = fn(...r) { len(r) }; g(f(), f())", 2},
+		{"let f = fn() {}; let g = fn(...r) { r[0] }; g(f())", nil},
+		{"let f = fn() {}; let g = fn(a = f()) { a }; g()", nil},
+		{"let f = fn() {}; let g = fn(a) { a }; g(f())", nil},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("no error object returned for %q. got=%T(%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		default:
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestSpreadExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let a = [1, 2]; let b = [3]; [...a, ...b, 4]", []int64{1, 2, 3, 4}},
+		{"[...[], 1, ...[]]", []int64{1}},
+		{"let a = [1, 2]; let b = [...a]; b[0] = 5; a", []int64{1, 2}},
+		{`[..."héllo"][1]`, "é"},
+		{"let add = fn(a, b, c) { a + b + c }; let args = [2, 3]; add(1, ...args)", 6},
+		{"let f = fn(...xs) { len(xs) }; f(...[1, 2], 3, ...[4])", 4},
+		{"[1, 2].push(...[3, 4])", "wrong number of arguments. got=3, want=2"},
+		{`let base = {"a": 1, "b": 2}; let h = {...base, "b": 3}; h["a"] * 10 + h["b"]`, 13},
+		{`let base = {"a": 1, "b": 2}; let h = {"b": 3, ...base}; h["b"]`, 2},
+		{`let base = {"a": 1}; let h = {...base}; h["a"] = 2; base["a"]`, 1},
+		{`let h = {"b": 2, "a": 1, 3: 0}; let keys = [...h]; keys[0] * 100 + len(keys[1] + keys[2])`, 302},
+		{`let f = fn(a, b) { a + b }; f(...{"x": 1, "y": 2})`, "xy"},
+		{"[...{}]", []int64{}},
+		{"[...5]", "cannot spread INTEGER, want ARRAY, STRING or HASH"},
+		{"let f = fn() {}; [...f()]", "cannot spread NULL, want ARRAY, STRING or HASH"},
+		{"let f = fn() {}; let g = fn(...r) { r }; g(...f())", "cannot spread NULL, want ARRAY, STRING or HASH"},
+		{"{...[1]}", "cannot spread ARRAY into a hash, want HASH"},
+		{"let f = fn() {}; {...f()}", "cannot spread NULL into a hash, want HASH"},
+		{"let f = fn() {}; let a = [...[f()], 1]; len(a)", 2},
+		{"let f = fn() {}; let h = {...{1: f()}, 2: f()}; len([...h])", 2},
+		{"let f = fn() {}; {f(): 1}", "unusable as hash key: NULL"},
+		{"[...missing]", "identifier not found: missing"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case []int64:
+			arr, ok := evaluated.(*object.Array)
+			if !ok {
+				t.Errorf("object is not Array. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if len(arr.Elements) != len(expected) {
+				t.Errorf("wrong array length for %q. expected=%d, got=%d", tt.input, len(expected), len(arr.Elements))
+				continue
+			}
+			for i, el := range arr.Elements {
+				testIntegerObject(t, el, expected[i])
+			}
+		case string:
+			switch obj := evaluated.(type) {
+			case *object.String:
+				if obj.Value != expected {
+					t.Errorf("String has wrong value. got=%q, want=%q", obj.Value, expected)
+				}
+			case *object.Error:
+				if obj.Message != expected {
+					t.Errorf("wrong error message. expected=%q, got=%q", expected, obj.Message)
+				}
+			default:
+				t.Errorf("object is not String or Error for %q. got=%T(%+v)", tt.input, evaluated, evaluated)
+			}
+		}
+	}
+}
+
+func TestLetPatterns(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let [a, b] = [1, 2]; a * 10 + b", 12},
+		{"let [a, b, ...rest] = [1, 2, 3, 4]; len(rest) * 100 + rest[0] * 10 + rest[1]", 234},
+		{"let [a, ...rest] = [1]; len(rest)", 0},
+		{"let [a, b] = [1]; b", nil},
+		{"let [a, b = a + 1] = [1]; b", 2},
+		{`let h = {}; let [a = 5] = [h["x"]]; a`, 5},
+		{"let [[a, b], c] = [[1, 2], 3]; a + b + c", 6},
+		{"let f = fn() { [3, 4] }; let [x, y] = f(); x * y", 12},
+		{"let xs = [1, 2, 3]; let [a, ...rest] = xs; rest[0] = 9; xs[1]", 2},
+		{`let user = {"name": "Ann", "age": 30}; let {name, age: years} = user; years`, 30},
+		{`let user = {"name": "Ann"}; let {age} = user; age`, nil},
+		{`let user = {"name": "Ann"}; let {age: years = 18} = user; years`, 18},
+		{`let user = {"address": {"city": 7}}; let {address: {city}} = user; city`, 7},
+		{`let rows = [{"id": 1, "tags": [5, 6]}]; let [{id, tags: [first, ...others]}] = rows; id + first + others[0]`, 12},
+		{"let [a, b] = 5", "cannot destructure INTEGER with an array pattern"},
+		{"let {a} = [1]", "cannot destructure ARRAY with a hash pattern"},
+		{"let [[a]] = []", "cannot destructure NULL with an array pattern"},
+		{"let f = fn() {}; let [a] = f()", "cannot destructure NULL with an array pattern"},
+		{"let f = fn() {}; let {a} = f()", "cannot destructure NULL with a hash pattern"},
+		{"let f = fn() {}; let [a = 3] = [f()]; a", 3},
+		{"let f = fn() {}; let x = f(); x", nil},
+		{"let [a = missing] = []", "identifier not found: missing"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("no error object returned for %q. got=%T(%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		default:
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestFunctionStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"fn add(x, y) { x + y }; add(2, 3)", 5},
+		{"let r = double(4); fn double(x) { x * 2 }; r", 8},
+		{`fn isEven(n) { if (n == 0) { true } else { isOdd(n - 1) } }
+		fn isOdd(n) { if (n == 0) { false } else { isEven(n - 1) } }
+		if (isEven(10)) { 1 } else { 0 }`, 1},
+		{"fn fact(n) { if (n < 2) { 1 } else { n * fact(n - 1) } }; fact(5)", 120},
+		{"let f = fn() { let r = g() + 1; fn g() { 41 }; r }; f()", 42},
+		{"let x = 1; fn getX() { x }; x = 2; getX()", 2},
+		{"fn outer() { fn inner() { 3 }; inner() }; inner()", "identifier not found: inner"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("no error object returned for %q. got=%T(%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		}
+	}
+}
+
 func TestStringLiteral(t *testing.T) {
 	input := `"Hello World!"`
 	evaluated := testEval(input)
@@ -344,6 +937,55 @@ func TestStringLiteral(t *testing.T) {
 	}
 }
 
+func TestRawAndMultilineStringLiterals(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"`a\\b` + \"!\"", `a\b!`},
+		{"let q = \"\"\"\n    SELECT *\n    FROM t\n    \"\"\"; q", "SELECT *\nFROM t"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		str, ok := evaluated.(*object.String)
+		if !ok {
+			t.Fatalf("object is not String. got=%T (%+v)", evaluated, evaluated)
+		}
+		if str.Value != tt.expected {
+			t.Errorf("String has wrong value. expected=%q, got=%q", tt.expected, str.Value)
+		}
+	}
+}
+
+func TestInterpolatedStrings(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let a = 2; let b = 3; "total: ${a + b} items"`, "total: 5 items"},
+		{`let name = "Monkey"; "hello ${name}!"`, "hello Monkey!"},
+		{`"${1.5} ${true} ${[1, "a"]} ${if (false) { 1 }}"`, "1.5 true [1, a] null"},
+		{`let f = fn(x) { "<${x}>" }; "${f("${f(1)}")}"`, "<<1>>"},
+		{`"no ${"interpolation"} \${here}"`, "no interpolation ${here}"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		str, ok := evaluated.(*object.String)
+		if !ok {
+			t.Fatalf("object is not String. got=%T (%+v)", evaluated, evaluated)
+		}
+		if str.Value != tt.expected {
+			t.Errorf("String has wrong value. expected=%q, got=%q", tt.expected, str.Value)
+		}
+	}
+
+	evaluated := testEval(`"value: ${missing}"`)
+	errObj, ok := evaluated.(*object.Error)
+	if !ok || errObj.Message != "identifier not found: missing" {
+		t.Errorf("expected identifier error. got=%T (%+v)", evaluated, evaluated)
+	}
+}
+
 func TestStringConcatenation(t *testing.T) {
 	input := `"Hello" + " " + "World!"`
 	evaluated := testEval(input)
@@ -395,6 +1037,7 @@ func TestBuiltinFunctions(t *testing.T) {
 		{`len("")`, 0},
 		{`len("four")`, 4},
 		{`len("hello world")`, 11},
+		{`len("héllo")`, 5},
 		{`len(1)`, "argument to `len` not supported, got INTEGER, want STRING or ARRAY"},
 		{`len(true)`, "argument to `len` not supported, got BOOLEAN, want STRING or ARRAY"},
 		{`len("one", "two")`, "wrong number of arguments. got=2, want=1"},
@@ -563,6 +1206,14 @@ func TestArrayIndexExpressions(t *testing.T) {
 			"let myArray = [1, 2, 3]; let i = myArray[0]; myArray[i]",
 			2,
 		},
+		{
+			"[1, 2, 3][-1]",
+			3,
+		},
+		{
+			"let myArray = [1, 2, 3]; myArray[-len(myArray)]",
+			1,
+		},
 	}
 	for _, tt := range tests {
 		evaluated := testEval(tt.input)
@@ -575,6 +1226,81 @@ func TestArrayIndexExpressions(t *testing.T) {
 	}
 }
 
+func TestStringIndexExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`"hello"[0]`, "h"},
+		{`"hello"[4]`, "o"},
+		{`"hello"[-1]`, "o"},
+		{`"héllo"[1]`, "é"},
+		{`let s = "héllo"; s[len(s) - 1]`, "o"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		str, ok := evaluated.(*object.String)
+		if !ok {
+			t.Errorf("object is not String. got=%T (%+v)", evaluated, evaluated)
+			continue
+		}
+		if str.Value != tt.expected {
+			t.Errorf("String has wrong value. got=%q, want=%q", str.Value, tt.expected)
+		}
+	}
+}
+
+func TestSliceExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"[1, 2, 3, 4][1:3]", []int64{2, 3}},
+		{"[1, 2, 3, 4][:2]", []int64{1, 2}},
+		{"[1, 2, 3, 4][2:]", []int64{3, 4}},
+		{"[1, 2, 3, 4][:]", []int64{1, 2, 3, 4}},
+		{"[1, 2, 3, 4][-2:]", []int64{3, 4}},
+		{"[1, 2, 3, 4][:-1]", []int64{1, 2, 3}},
+		{"[1, 2, 3, 4][1:10]", []int64{2, 3, 4}},
+		{"[1, 2, 3, 4][-10:1]", []int64{1}},
+		{"[1, 2, 3, 4][3:1]", []int64{}},
+		{"let a = [1, 2, 3]; let b = a[:]; b[0] = 9; a", []int64{1, 2, 3}},
+		{`"hello"[1:3]`, "el"},
+		{`"hello"[-3:]`, "llo"},
+		{`"héllo"[:2]`, "hé"},
+		{`"hello"[4:2]`, ""},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case []int64:
+			arr, ok := evaluated.(*object.Array)
+			if !ok {
+				t.Errorf("object is not Array. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if len(arr.Elements) != len(expected) {
+				t.Errorf("wrong array length for %q. expected=%d, got=%d", tt.input, len(expected), len(arr.Elements))
+				continue
+			}
+			for i, el := range arr.Elements {
+				testIntegerObject(t, el, expected[i])
+			}
+		case string:
+			str, ok := evaluated.(*object.String)
+			if !ok {
+				t.Errorf("object is not String. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if str.Value != expected {
+				t.Errorf("String has wrong value. got=%q, want=%q", str.Value, expected)
+			}
+		}
+	}
+}
+
 func TestHashLiterals(t *testing.T) {
 	input := `let two = "two";
 	{
@@ -744,3 +1470,28 @@ func testNullObject(t *testing.T, obj object.Object) bool {
 	}
 	return true
 }
+
+func TestErrorPositions(t *testing.T) {
+	tests := []struct {
+		input       string
+		expectedPos string
+	}{
+		{"5 + true;", "1:1"},
+		{"let x = 1;\nlet y = -true;", "2:9"},
+		{"let f = fn() {\n  foobar\n};\nf();", "2:3"},
+		{"[1, 2][5]", "1:1"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned. got=%T(%+v)",
+				evaluated, evaluated)
+			continue
+		}
+		if errObj.Pos.String() != tt.expectedPos {
+			t.Errorf("wrong error position for %q. expected=%s, got=%s",
+				tt.input, tt.expectedPos, errObj.Pos)
+		}
+	}
+}
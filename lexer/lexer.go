@@ -1,49 +1,213 @@
 package lexer
 
 import (
+	"bufio"
+	"fmt"
+	"io"
 	"monkey/token"
+	"strconv"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
 // Lexer the lexer type
 type Lexer struct {
-	input        string
-	position     int  // current position in input (points to current char)
-	readPosition int  // current reading position in input (after current char)
-	ch           byte // current char under examination
+	reader       *bufio.Reader
+	filename     string // name of the file being lexed, used in token positions
+	position     int    // current byte position in input (points to current char)
+	readPosition int    // current reading byte position in input (after current char)
+	ch           rune   // current char under examination
+	line         int    // line of the current char
+	column       int    // column of the current char, counted in chars (runes)
+	atEOF        bool   // the whole input has been read
+	err          error  // error returned by the reader, other than io.EOF
+
+	// the char after the current char, once peeked at
+	peekCh    rune
+	peekWidth int
+	peeked    bool
+
+	// chars consumed by readChar while capturing a literal
+	capture *strings.Builder
+
+	// open brace count of each ${ interpolation being lexed, innermost last
+	interpolations []int
+
+	// an unterminated block comment read after the last token, reported as the next token
+	unterminated *token.Comment
 }
 
 // NewLexer creates and returns a Lexer
 func NewLexer(input string) *Lexer {
-	l := &Lexer{input: input}
+	return NewFileLexer("", input)
+}
+
+// NewFileLexer creates and returns a Lexer whose token positions refer to the given file name
+func NewFileLexer(filename string, input string) *Lexer {
+	return NewReaderLexer(filename, strings.NewReader(input))
+}
+
+// NewReaderLexer creates and returns a Lexer that reads its input from r as it goes,
+// through a buffer, rather than loading it all at once
+func NewReaderLexer(filename string, r io.Reader) *Lexer {
+	l := &Lexer{reader: bufio.NewReader(r), filename: filename, line: 1}
 	l.readChar()
 	return l
 }
 
+// readChar decodes the next UTF-8 encoded char from the input
 func (l *Lexer) readChar() {
-	if l.readPosition >= len(l.input) {
-		l.ch = 0
+	if l.atEOF {
+		return
+	}
+	if l.capture != nil {
+		l.capture.WriteRune(l.ch)
+	}
+	if l.ch == '\n' {
+		l.line++
+		l.column = 0
+	}
+	l.column++
+
+	var width int
+	if l.peeked {
+		l.ch, width = l.peekCh, l.peekWidth
+		l.peeked = false
 	} else {
-		l.ch = l.input[l.readPosition]
+		l.ch, width = l.readRune()
 	}
+	l.atEOF = width == 0
 	l.position = l.readPosition
 
-	l.readPosition++
+	l.readPosition += width
 }
 
 // peekChar returns the char after the current char if there's one
-func (l *Lexer) peekChar() byte {
-	if l.readPosition >= len(l.input) {
-		return 0
+func (l *Lexer) peekChar() rune {
+	if !l.peeked {
+		l.peekCh, l.peekWidth = l.readRune()
+		l.peeked = true
+	}
+	return l.peekCh
+}
+
+// readRune reads a char and its width in bytes from the reader,
+// or 0 and a width of 0 when there is nothing left to read
+func (l *Lexer) readRune() (rune, int) {
+	ch, width, err := l.reader.ReadRune()
+	if err != nil {
+		if err != io.EOF && l.err == nil {
+			l.err = err
+		}
+		return 0, 0
+	}
+	return ch, width
+}
+
+// startCapture starts recording the chars consumed by readChar, from the current char on
+func (l *Lexer) startCapture() {
+	l.capture = &strings.Builder{}
+}
+
+// endCapture stops recording the consumed chars and returns them
+func (l *Lexer) endCapture() string {
+	captured := l.capture.String()
+	l.capture = nil
+	return captured
+}
+
+// Tokenize returns all the tokens of the source, up to and including the EOF token
+func Tokenize(src string) token.Tokens {
+	return NewLexer(src).Tokens()
+}
+
+// Tokens reads and returns the remaining tokens, up to and including the EOF token
+func (l *Lexer) Tokens() token.Tokens {
+	tokens := token.Tokens{}
+	for {
+		tok := l.NextToken()
+		tokens = append(tokens, tok)
+		if tok.Type == token.EOF {
+			return tokens
+		}
 	}
-	return l.input[l.readPosition]
 }
 
-// NextToken returns the next token
+// curPosition returns the source position of the current char
+func (l *Lexer) curPosition() token.Position {
+	return token.Position{Filename: l.filename, Offset: l.position, Line: l.line, Column: l.column}
+}
+
+// NextToken returns the next token, along with its start and end positions,
+// the comments that precede it and those that follow it on its line
 func (l *Lexer) NextToken() token.Token {
-	var tok token.Token
+	if comment := l.unterminated; comment != nil {
+		l.unterminated = nil
+		return unterminatedComment(*comment, nil)
+	}
+
+	var comments []token.Comment
+	for {
+		l.skipWhitespace()
+		if !l.atComment() {
+			break
+		}
+		comment, ok := l.readComment()
+		if !ok {
+			return unterminatedComment(comment, comments)
+		}
+		comments = append(comments, comment)
+	}
 
-	l.skipWhitespace()
+	pos := l.curPosition()
+	tok := l.readToken()
+	tok.Pos = pos
+	tok.End = l.curPosition()
+	if tok.Type == token.EOF {
+		tok.End = pos
+	}
+	tok.Comments = comments
+	if tok.Type != token.EOF && tok.Type != token.ILLEGAL {
+		tok.Trailing = l.readTrailingComments()
+	}
+	return tok
+}
+
+// readTrailingComments reads the comments starting on the current line, up to the
+// end of a line comment or the first char that is not part of a comment
+func (l *Lexer) readTrailingComments() []token.Comment {
+	var comments []token.Comment
+	for {
+		for l.ch == ' ' || l.ch == '\t' || l.ch == '\r' {
+			l.readChar()
+		}
+		if !l.atComment() {
+			return comments
+		}
+		comment, ok := l.readComment()
+		if !ok {
+			l.unterminated = &comment
+			return comments
+		}
+		comments = append(comments, comment)
+		if !comment.IsBlock() {
+			return comments
+		}
+	}
+}
+
+// unterminatedComment returns the ILLEGAL token for a block comment that is not
+// terminated, preceded by the given comments
+func unterminatedComment(comment token.Comment, comments []token.Comment) token.Token {
+	return token.Token{Type: token.ILLEGAL, Literal: comment.Text,
+		Pos: comment.Pos, End: comment.End, Comments: comments,
+		Error: "unterminated block comment"}
+}
+
+// readToken reads the token starting at the current char
+func (l *Lexer) readToken() token.Token {
+	var tok token.Token
 
 	switch l.ch {
 	case '=':
@@ -64,11 +228,27 @@ func (l *Lexer) NextToken() token.Token {
 	case ',':
 		tok = newToken(token.COMMA, l.ch)
 	case '.':
-		tok = newToken(token.PERIOD, l.ch)
+		if isDigit(l.peekChar()) {
+			return l.readNumber()
+		}
+		if l.peekChar() != '.' {
+			tok = newToken(token.PERIOD, l.ch)
+			break
+		}
+		l.readChar()
+		if l.peekChar() == '.' {
+			l.readChar()
+			tok = token.Token{Type: token.ELLIPSIS, Literal: "..."}
+		} else {
+			tok = token.Token{Type: token.ILLEGAL, Literal: "..", Error: `illegal token ".."`}
+		}
 	case '+':
 		if l.peekChar() == '=' {
 			l.readChar()
 			tok = token.Token{Type: token.PLUSEQ, Literal: "+="}
+		} else if l.peekChar() == '+' {
+			l.readChar()
+			tok = token.Token{Type: token.INCREMENT, Literal: "++"}
 		} else {
 			tok = newToken(token.PLUS, l.ch)
 		}
@@ -76,6 +256,9 @@ func (l *Lexer) NextToken() token.Token {
 		if l.peekChar() == '=' {
 			l.readChar()
 			tok = token.Token{Type: token.MINUSEQ, Literal: "-="}
+		} else if l.peekChar() == '-' {
+			l.readChar()
+			tok = token.Token{Type: token.DECREMENT, Literal: "--"}
 		} else {
 			tok = newToken(token.MINUS, l.ch)
 		}
@@ -104,6 +287,9 @@ func (l *Lexer) NextToken() token.Token {
 		if l.peekChar() == '=' {
 			l.readChar()
 			tok = token.Token{Type: token.LTEQ, Literal: "<="}
+		} else if l.peekChar() == '<' {
+			l.readChar()
+			tok = token.Token{Type: token.SHL, Literal: "<<"}
 		} else {
 			tok = newToken(token.LT, l.ch)
 		}
@@ -111,16 +297,55 @@ func (l *Lexer) NextToken() token.Token {
 		if l.peekChar() == '=' {
 			l.readChar()
 			tok = token.Token{Type: token.GTEQ, Literal: ">="}
+		} else if l.peekChar() == '>' {
+			l.readChar()
+			tok = token.Token{Type: token.SHR, Literal: ">>"}
 		} else {
 			tok = newToken(token.GT, l.ch)
 		}
+	case '&':
+		if l.peekChar() == '&' {
+			l.readChar()
+			tok = token.Token{Type: token.AND, Literal: "&&"}
+		} else {
+			tok = newToken(token.BITAND, l.ch)
+		}
+	case '|':
+		if l.peekChar() == '|' {
+			l.readChar()
+			tok = token.Token{Type: token.OR, Literal: "||"}
+		} else {
+			tok = newToken(token.BITOR, l.ch)
+		}
+	case '~':
+		tok = newToken(token.BITNOT, l.ch)
+	case '?':
+		if l.peekChar() == '?' {
+			l.readChar()
+			tok = token.Token{Type: token.NULLISH, Literal: "??"}
+		} else {
+			tok = newToken(token.QUESTION, l.ch)
+		}
 	case '^':
 		tok = token.Token{Type: token.POWER, Literal: "^"}
 	case '%':
 		tok = token.Token{Type: token.MODULUS, Literal: "%"}
 	case '{':
+		if n := len(l.interpolations); n > 0 {
+			l.interpolations[n-1]++
+		}
 		tok = newToken(token.LBRACE, l.ch)
 	case '}':
+		n := len(l.interpolations)
+		if n > 0 && l.interpolations[n-1] == 0 {
+			// end of an interpolation, back to the string's text
+			l.interpolations = l.interpolations[:n-1]
+			tok = l.readStringToken(token.STRINGEND, token.STRINGMIDDLE)
+			break
+		}
+		if n > 0 {
+			l.interpolations[n-1]--
+		}
 		tok = newToken(token.RBRACE, l.ch)
 	case '[':
 		tok = newToken(token.LBRACKET, l.ch)
@@ -129,88 +354,430 @@ func (l *Lexer) NextToken() token.Token {
 	case 0:
 		tok.Literal = ""
 		tok.Type = token.EOF
+		if l.err != nil {
+			tok.Type = token.ILLEGAL
+			tok.Error = fmt.Sprintf("read error: %s", l.err)
+			l.err = nil
+		}
 	case '"':
 		tok.Type = token.STRING
-		tok.Literal = l.readString()
+		if l.peekChar() == '"' {
+			l.readChar()
+			if l.peekChar() != '"' {
+				// the empty string: ""
+				break
+			}
+			l.readChar()
+			tok.Literal, tok.Error = l.readTripleQuotedString()
+		} else {
+			tok = l.readStringToken(token.STRING, token.STRINGSTART)
+		}
+		if tok.Error != "" {
+			tok.Type = token.ILLEGAL
+		}
+	case '`':
+		tok.Type = token.STRING
+		tok.Literal, tok.Error = l.readRawString()
+		if tok.Error != "" {
+			tok.Type = token.ILLEGAL
+		}
 	default:
 		if isLetter(l.ch) {
 			tok.Literal = l.readIdentifier()
 			tok.LookupIdent()
 			return tok
 		} else if isDigit(l.ch) {
-			tok.Literal = l.readNumber()
-			tok.Type = token.INT
-			return tok
+			return l.readNumber()
 		}
-		tok = newToken(token.ILLEGAL, l.ch)
+		tok = l.illegalChar()
 	}
 	l.readChar()
 	return tok
 }
 
+// illegalChar returns an ILLEGAL token for the current char
+func (l *Lexer) illegalChar() token.Token {
+	tok := newToken(token.ILLEGAL, l.ch)
+	if l.ch == utf8.RuneError {
+		tok.Error = "invalid UTF-8 encoding"
+	} else {
+		tok.Error = fmt.Sprintf("illegal character %q", l.ch)
+	}
+	return tok
+}
+
 // newToken creates a token given its type and the character
-func newToken(tokenType token.Type, ch byte) token.Token {
+func newToken(tokenType token.Type, ch rune) token.Token {
 	return token.Token{Type: tokenType, Literal: string(ch)}
 }
 
-func (l *Lexer) readString() string {
-	position := l.position + 1
+// readStringToken reads the text of a double quoted string up to its closing quote,
+// giving a token of type closed, or up to the next ${ interpolation, giving a token
+// of type open
+func (l *Lexer) readStringToken(closed token.Type, open token.Type) token.Token {
+	tok := token.Token{Type: closed}
+	var interpolated bool
+	tok.Literal, interpolated, tok.Error = l.readString()
+	if interpolated {
+		tok.Type = open
+		l.interpolations = append(l.interpolations, 0)
+	}
+	if tok.Error != "" {
+		tok.Type = token.ILLEGAL
+	}
+	return tok
+}
+
+// readString reads a double quoted string and decodes its escape sequences:
+// \\ \" \$ \n \t \r \0, and the \xNN and \u{N...} code point escapes.
+// It stops at the closing quote or, returning true, after the ${ opening an interpolation.
+// It also returns an error message if the string is not terminated or contains an
+// invalid escape sequence
+func (l *Lexer) readString() (string, bool, string) {
+	var out strings.Builder
+	msg := ""
 
 	for {
 		l.readChar()
-		if (l.ch == '"' && l.input[l.position-1] != '\\') || l.ch == 0 {
-			break
+		switch l.ch {
+		case 0:
+			return out.String(), false, "unterminated string literal"
+		case '"':
+			return out.String(), false, msg
+		case '$':
+			if l.peekChar() == '{' {
+				l.readChar()
+				return out.String(), true, msg
+			}
+			out.WriteRune(l.ch)
+		case '\\':
+			l.readChar()
+			if l.ch == 0 {
+				return out.String(), false, "unterminated string literal"
+			}
+			if escapeMsg := l.readEscape(&out); msg == "" {
+				msg = escapeMsg
+			}
+		default:
+			out.WriteRune(l.ch)
 		}
 	}
-	replacements := []struct {
-		Find    string
-		Replace string
-	}{
-		{Find: "\\\"", Replace: "\""},
-		{Find: "\\n", Replace: "\n"},
-		{Find: "\\t", Replace: "\t"},
-		{Find: "\\r", Replace: "\r"},
+}
+
+// readEscape decodes the escape sequence starting at the current char, the one after
+// the backslash, into out. It returns an error message if the escape sequence is invalid
+func (l *Lexer) readEscape(out *strings.Builder) string {
+	switch l.ch {
+	case '\\', '"', '$':
+		out.WriteRune(l.ch)
+	case 'n':
+		out.WriteRune('\n')
+	case 't':
+		out.WriteRune('\t')
+	case 'r':
+		out.WriteRune('\r')
+	case '0':
+		out.WriteRune(0)
+	case 'x':
+		digits := l.readHexDigits(2)
+		if len(digits) != 2 {
+			return `invalid escape sequence \x: expected 2 hex digits`
+		}
+		code, _ := strconv.ParseUint(digits, 16, 8)
+		out.WriteRune(rune(code))
+	case 'u':
+		if l.peekChar() != '{' {
+			return `invalid escape sequence \u: expected \u{...}`
+		}
+		l.readChar()
+		digits := l.readHexDigits(6)
+		if len(digits) == 0 || l.peekChar() != '}' {
+			return `invalid escape sequence \u{...}: expected 1 to 6 hex digits`
+		}
+		l.readChar()
+		code, _ := strconv.ParseUint(digits, 16, 32)
+		if !utf8.ValidRune(rune(code)) {
+			return fmt.Sprintf(`invalid escape sequence \u{%s}: not a valid code point`, digits)
+		}
+		out.WriteRune(rune(code))
+	default:
+		return fmt.Sprintf("unknown escape sequence %q", "\\"+string(l.ch))
 	}
-	var value string = l.input[position:l.position]
+	return ""
+}
 
-	for _, replacement := range replacements {
-		value = strings.ReplaceAll(value, replacement.Find, replacement.Replace)
+// readRawString reads a `backtick` string as is: there are no escape sequences and it may
+// span several lines. It returns an error message if the string is not terminated
+func (l *Lexer) readRawString() (string, string) {
+	var out strings.Builder
+	for {
+		l.readChar()
+		switch l.ch {
+		case 0:
+			return out.String(), "unterminated raw string literal"
+		case '`':
+			return out.String(), ""
+		default:
+			out.WriteRune(l.ch)
+		}
 	}
+}
 
-	return value
+// readTripleQuotedString reads a """triple quoted""" string, the current char being the
+// last of the opening quotes. Like raw strings it has no escape sequences and may span
+// several lines, but its common leading indentation is stripped (see trimIndent).
+// It returns an error message if the string is not terminated
+func (l *Lexer) readTripleQuotedString() (string, string) {
+	var out strings.Builder
+	quotes := 0
+	for {
+		l.readChar()
+		switch l.ch {
+		case 0:
+			out.WriteString(strings.Repeat(`"`, quotes))
+			return trimIndent(out.String()), "unterminated triple quoted string literal"
+		case '"':
+			quotes++
+			if quotes == 3 {
+				return trimIndent(out.String()), ""
+			}
+		default:
+			out.WriteString(strings.Repeat(`"`, quotes))
+			quotes = 0
+			out.WriteRune(l.ch)
+		}
+	}
+}
+
+// trimIndent strips the line break that follows the opening quotes of a triple quoted
+// string, the whitespace-only line holding its closing quotes, and the indentation
+// shared by all its non-blank lines
+func trimIndent(s string) string {
+	lines := strings.Split(s, "\n")
+	first := 0
+	if len(lines) > 1 && strings.TrimSpace(lines[0]) == "" {
+		lines = lines[1:]
+	} else {
+		// text right after the opening quotes is kept as is
+		first = 1
+	}
+	if last := len(lines) - 1; last > 0 && strings.TrimSpace(lines[last]) == "" {
+		lines = lines[:last]
+	}
+
+	indent := ""
+	found := false
+	for _, line := range lines[first:] {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		lineIndent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+		if !found {
+			indent, found = lineIndent, true
+			continue
+		}
+		for !strings.HasPrefix(lineIndent, indent) {
+			indent = indent[:len(indent)-1]
+		}
+	}
+
+	for i := first; i < len(lines); i++ {
+		if strings.TrimSpace(lines[i]) == "" {
+			lines[i] = ""
+		} else {
+			lines[i] = lines[i][len(indent):]
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// readHexDigits reads and returns up to max hex digits following the current char
+func (l *Lexer) readHexDigits(max int) string {
+	var digits strings.Builder
+	for digits.Len() < max && isHexDigit(l.peekChar()) {
+		l.readChar()
+		digits.WriteRune(l.ch)
+	}
+	return digits.String()
 }
 
 // readIdentifier reads and returns an identifier from the input
 func (l *Lexer) readIdentifier() string {
-	position := l.position
-	for isLetter(l.ch) {
+	l.startCapture()
+	for isLetter(l.ch) || unicode.IsMark(l.ch) {
 		l.readChar()
 	}
-	return l.input[position:l.position]
+	return l.endCapture()
 }
 
-// isLetter returns true if the char is an alphabet or underscore
-func isLetter(ch byte) bool {
-	return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch == '_'
+// isLetter returns true if the char is a Unicode letter or underscore
+func isLetter(ch rune) bool {
+	return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch == '_' ||
+		ch >= utf8.RuneSelf && unicode.IsLetter(ch)
+}
+
+// readNumber reads a number from the input and returns it as an INT or DOUBLE token.
+// Integers may have a 0x, 0o or 0b base prefix, doubles a fraction and/or an exponent,
+// and digits may be separated with underscores: 0xff, 1_000, .5, 1.5e-3.
+// Malformed numbers are returned as ILLEGAL tokens
+func (l *Lexer) readNumber() token.Token {
+	l.startCapture()
+	tok := token.Token{Type: token.INT}
+	prefixed := false
+
+	if l.ch == '0' && isBasePrefix(l.peekChar()) {
+		prefixed = true
+		l.readChar()
+		isBaseDigit := baseDigitFn(l.ch)
+		l.readChar()
+		if !l.readDigits(isBaseDigit) {
+			tok.Error = "missing digits after base prefix"
+		}
+	} else {
+		l.readDigits(isDigit)
+		if l.ch == '.' && isDigit(l.peekChar()) {
+			tok.Type = token.DOUBLE
+			l.readChar()
+			l.readDigits(isDigit)
+		}
+		if l.ch == 'e' || l.ch == 'E' {
+			tok.Type = token.DOUBLE
+			l.readChar()
+			if l.ch == '+' || l.ch == '-' {
+				l.readChar()
+			}
+			if !l.readDigits(isDigit) {
+				tok.Error = "exponent has no digits"
+			}
+		}
+	}
+
+	// letters or digits stuck to the number, e.g. 0b102 or 12abc
+	if isLetter(l.ch) || isDigit(l.ch) {
+		if tok.Error == "" {
+			tok.Error = fmt.Sprintf("invalid character %q in number", l.ch)
+		}
+		for isLetter(l.ch) || isDigit(l.ch) {
+			l.readChar()
+		}
+	}
+
+	tok.Literal = l.endCapture()
+	if tok.Error == "" && !validSeparators(tok.Literal, prefixed) {
+		tok.Error = "'_' must separate successive digits"
+	}
+	if tok.Error != "" {
+		tok.Type = token.ILLEGAL
+		tok.Error = fmt.Sprintf("malformed number %q: %s", tok.Literal, tok.Error)
+	}
+	return tok
 }
 
-// readNumber reads and returns a number from the input
-func (l *Lexer) readNumber() string {
-	position := l.position
-	for isDigit(l.ch) {
+// readDigits reads digits matching isValid, along with '_' separators,
+// and returns true if at least one digit was read
+func (l *Lexer) readDigits(isValid func(rune) bool) bool {
+	found := false
+	for isValid(l.ch) || l.ch == '_' {
+		if l.ch != '_' {
+			found = true
+		}
 		l.readChar()
 	}
-	return l.input[position:l.position]
+	return found
+}
+
+// validSeparators returns true if every '_' in the number literal sits between two digits,
+// or right after a base prefix
+func validSeparators(literal string, prefixed bool) bool {
+	isValid := isDigit
+	if prefixed {
+		isValid = isHexDigit
+	}
+	for i := 0; i < len(literal); i++ {
+		if literal[i] != '_' {
+			continue
+		}
+		afterPrefix := prefixed && i == 2
+		if i == len(literal)-1 || !isValid(rune(literal[i+1])) || !(afterPrefix || isValid(rune(literal[i-1]))) {
+			return false
+		}
+	}
+	return true
+}
+
+// isBasePrefix returns true if the char follows a 0 to denote a hex, octal or binary number
+func isBasePrefix(ch rune) bool {
+	switch ch {
+	case 'x', 'X', 'o', 'O', 'b', 'B':
+		return true
+	}
+	return false
+}
+
+// baseDigitFn returns the digit check for the given base prefix char
+func baseDigitFn(prefix rune) func(rune) bool {
+	switch prefix {
+	case 'x', 'X':
+		return isHexDigit
+	case 'o', 'O':
+		return func(ch rune) bool { return '0' <= ch && ch <= '7' }
+	default:
+		return func(ch rune) bool { return ch == '0' || ch == '1' }
+	}
 }
 
 // isDigit returns true if the char is a digit
-func isDigit(ch byte) bool {
+func isDigit(ch rune) bool {
 	return '0' <= ch && ch <= '9'
 }
 
+// isHexDigit returns true if the char is a hexadecimal digit
+func isHexDigit(ch rune) bool {
+	return isDigit(ch) || 'a' <= ch && ch <= 'f' || 'A' <= ch && ch <= 'F'
+}
+
 // skipWhitespace skips whitespace from the input
 func (l *Lexer) skipWhitespace() {
 	for l.ch == ' ' || l.ch == '\t' || l.ch == '\n' || l.ch == '\r' {
 		l.readChar()
 	}
 }
+
+// atComment returns true if a comment starts at the current char
+func (l *Lexer) atComment() bool {
+	return l.ch == '/' && (l.peekChar() == '/' || l.peekChar() == '*')
+}
+
+// readComment reads a // line comment or a, possibly nested, /* block */ comment.
+// It returns false if a block comment is not terminated before the end of the input
+func (l *Lexer) readComment() (token.Comment, bool) {
+	comment := token.Comment{Pos: l.curPosition()}
+	l.startCapture()
+
+	l.readChar()
+	if l.ch == '/' {
+		for l.ch != '\n' && l.ch != 0 {
+			l.readChar()
+		}
+		comment.Text = l.endCapture()
+		comment.End = l.curPosition()
+		return comment, true
+	}
+
+	l.readChar()
+	depth := 1
+	for depth > 0 && l.ch != 0 {
+		switch {
+		case l.ch == '/' && l.peekChar() == '*':
+			l.readChar()
+			depth++
+		case l.ch == '*' && l.peekChar() == '/':
+			l.readChar()
+			depth--
+		}
+		l.readChar()
+	}
+	comment.Text = l.endCapture()
+	comment.End = l.curPosition()
+	return comment, depth == 0
+}
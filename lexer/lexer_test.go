@@ -1,7 +1,11 @@
 package lexer
 
 import (
+	"errors"
+	"io"
+	"strings"
 	"testing"
+	"testing/iotest"
 
 	"monkey/token"
 )
@@ -15,7 +19,7 @@ func TestNextToken(t *testing.T) {
 	};
 
 	let result = add(five, ten);
-	!-/*5;
+	!-/ *5;
 	5 < 10 > 5;
 
 	if (5 < 10) {
@@ -37,6 +41,10 @@ func TestNextToken(t *testing.T) {
 	9.11;
 	-9.11;
 	10%5;
+	a && b || c ?? d;
+	~a & b | c xor d << 1 >> 2;
+	i++ + --j;
+	fn(a, ...b) {};
 	`
 	tests := []struct {
 		expectedType    token.Type
@@ -167,15 +175,11 @@ func TestNextToken(t *testing.T) {
 		{token.INT, "9"},
 		{token.SEMICOLON, ";"},
 
-		{token.INT, "9"},
-		{token.PERIOD, "."},
-		{token.INT, "11"},
+		{token.DOUBLE, "9.11"},
 		{token.SEMICOLON, ";"},
 
 		{token.MINUS, "-"},
-		{token.INT, "9"},
-		{token.PERIOD, "."},
-		{token.INT, "11"},
+		{token.DOUBLE, "9.11"},
 		{token.SEMICOLON, ";"},
 
 		{token.INT, "10"},
@@ -183,6 +187,47 @@ func TestNextToken(t *testing.T) {
 		{token.INT, "5"},
 		{token.SEMICOLON, ";"},
 
+		{token.IDENT, "a"},
+		{token.AND, "&&"},
+		{token.IDENT, "b"},
+		{token.OR, "||"},
+		{token.IDENT, "c"},
+		{token.NULLISH, "??"},
+		{token.IDENT, "d"},
+		{token.SEMICOLON, ";"},
+
+		{token.BITNOT, "~"},
+		{token.IDENT, "a"},
+		{token.BITAND, "&"},
+		{token.IDENT, "b"},
+		{token.BITOR, "|"},
+		{token.IDENT, "c"},
+		{token.XOR, "xor"},
+		{token.IDENT, "d"},
+		{token.SHL, "<<"},
+		{token.INT, "1"},
+		{token.SHR, ">>"},
+		{token.INT, "2"},
+		{token.SEMICOLON, ";"},
+
+		{token.IDENT, "i"},
+		{token.INCREMENT, "++"},
+		{token.PLUS, "+"},
+		{token.DECREMENT, "--"},
+		{token.IDENT, "j"},
+		{token.SEMICOLON, ";"},
+
+		{token.FUNCTION, "fn"},
+		{token.LPAREN, "("},
+		{token.IDENT, "a"},
+		{token.COMMA, ","},
+		{token.ELLIPSIS, "..."},
+		{token.IDENT, "b"},
+		{token.RPAREN, ")"},
+		{token.LBRACE, "{"},
+		{token.RBRACE, "}"},
+		{token.SEMICOLON, ";"},
+
 		{token.EOF, ""},
 	}
 
@@ -234,3 +279,399 @@ func TestNextStringToken(t *testing.T) {
 		}
 	}
 }
+
+func TestTokenPositions(t *testing.T) {
+	input := "let x = 5;\n  x >= 10;"
+	tests := []struct {
+		expectedType   token.Type
+		expectedOffset int
+		expectedPos    string
+		expectedEnd    string
+	}{
+		{token.LET, 0, "test.mk:1:1", "test.mk:1:4"},
+		{token.IDENT, 4, "test.mk:1:5", "test.mk:1:6"},
+		{token.ASSIGN, 6, "test.mk:1:7", "test.mk:1:8"},
+		{token.INT, 8, "test.mk:1:9", "test.mk:1:10"},
+		{token.SEMICOLON, 9, "test.mk:1:10", "test.mk:1:11"},
+		{token.IDENT, 13, "test.mk:2:3", "test.mk:2:4"},
+		{token.GTEQ, 15, "test.mk:2:5", "test.mk:2:7"},
+		{token.INT, 18, "test.mk:2:8", "test.mk:2:10"},
+		{token.SEMICOLON, 20, "test.mk:2:10", "test.mk:2:11"},
+		{token.EOF, 21, "test.mk:2:11", "test.mk:2:11"},
+	}
+
+	l := NewFileLexer("test.mk", input)
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+		if tok.Pos.Offset != tt.expectedOffset {
+			t.Fatalf("tests[%d] - offset wrong. expected=%d, got=%d",
+				i, tt.expectedOffset, tok.Pos.Offset)
+		}
+		if tok.Pos.String() != tt.expectedPos {
+			t.Fatalf("tests[%d] - pos wrong. expected=%s, got=%s",
+				i, tt.expectedPos, tok.Pos)
+		}
+		if tok.End.String() != tt.expectedEnd {
+			t.Fatalf("tests[%d] - end wrong. expected=%s, got=%s",
+				i, tt.expectedEnd, tok.End)
+		}
+	}
+}
+
+func TestComments(t *testing.T) {
+	input := `// leading comment
+	let x = 5; // trailing comment
+	/* block /* nested */ comment */ x /* inline */ / 2; /* spans
+	lines */ /* and more */ // line
+	y; /* unterminated`
+	tests := []struct {
+		expectedType     token.Type
+		expectedLiteral  string
+		expectedComments []string
+		expectedTrailing []string
+	}{
+		{token.LET, "let", []string{"// leading comment"}, nil},
+		{token.IDENT, "x", nil, nil},
+		{token.ASSIGN, "=", nil, nil},
+		{token.INT, "5", nil, nil},
+		{token.SEMICOLON, ";", nil, []string{"// trailing comment"}},
+		{token.IDENT, "x", []string{"/* block /* nested */ comment */"}, []string{"/* inline */"}},
+		{token.SLASH, "/", nil, nil},
+		{token.INT, "2", nil, nil},
+		{token.SEMICOLON, ";", nil, []string{"/* spans\n\tlines */", "/* and more */", "// line"}},
+		{token.IDENT, "y", nil, nil},
+		{token.SEMICOLON, ";", nil, nil},
+		{token.ILLEGAL, "/* unterminated", nil, nil},
+		{token.EOF, "", nil, nil},
+	}
+
+	l := NewLexer(input)
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+		if len(tok.Comments) != len(tt.expectedComments) {
+			t.Fatalf("tests[%d] - wrong number of comments. expected=%d, got=%d",
+				i, len(tt.expectedComments), len(tok.Comments))
+		}
+		for j, comment := range tok.Comments {
+			if comment.Text != tt.expectedComments[j] {
+				t.Fatalf("tests[%d] - comment[%d] wrong. expected=%q, got=%q",
+					i, j, tt.expectedComments[j], comment.Text)
+			}
+		}
+		if len(tok.Trailing) != len(tt.expectedTrailing) {
+			t.Fatalf("tests[%d] - wrong number of trailing comments. expected=%d, got=%d",
+				i, len(tt.expectedTrailing), len(tok.Trailing))
+		}
+		for j, comment := range tok.Trailing {
+			if comment.Text != tt.expectedTrailing[j] {
+				t.Fatalf("tests[%d] - trailing comment[%d] wrong. expected=%q, got=%q",
+					i, j, tt.expectedTrailing[j], comment.Text)
+			}
+		}
+	}
+}
+
+func TestNumberTokens(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedType    token.Type
+		expectedLiteral string
+		expectedError   string
+	}{
+		{"42", token.INT, "42", ""},
+		{"1_000_000", token.INT, "1_000_000", ""},
+		{"0xff", token.INT, "0xff", ""},
+		{"0XFF_FF", token.INT, "0XFF_FF", ""},
+		{"0o755", token.INT, "0o755", ""},
+		{"0b1010", token.INT, "0b1010", ""},
+		{"1.05", token.DOUBLE, "1.05", ""},
+		{".5", token.DOUBLE, ".5", ""},
+		{"1e9", token.DOUBLE, "1e9", ""},
+		{"1.5E-3", token.DOUBLE, "1.5E-3", ""},
+		{"2e+10", token.DOUBLE, "2e+10", ""},
+		{"1_000.000_1", token.DOUBLE, "1_000.000_1", ""},
+		{"0x", token.ILLEGAL, "0x", `malformed number "0x": missing digits after base prefix`},
+		{"0b102", token.ILLEGAL, "0b102", `malformed number "0b102": invalid character '2' in number`},
+		{"1e", token.ILLEGAL, "1e", `malformed number "1e": exponent has no digits`},
+		{"12abc", token.ILLEGAL, "12abc", `malformed number "12abc": invalid character 'a' in number`},
+		{"1__0", token.ILLEGAL, "1__0", `malformed number "1__0": '_' must separate successive digits`},
+		{"10_", token.ILLEGAL, "10_", `malformed number "10_": '_' must separate successive digits`},
+	}
+
+	for i, tt := range tests {
+		l := NewLexer(tt.input)
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+		if tok.Error != tt.expectedError {
+			t.Fatalf("tests[%d] - error wrong. expected=%q, got=%q",
+				i, tt.expectedError, tok.Error)
+		}
+		if next := l.NextToken(); next.Type != token.EOF {
+			t.Fatalf("tests[%d] - expected EOF after number. got=%q", i, next.Type)
+		}
+	}
+}
+
+func TestUnicodeTokens(t *testing.T) {
+	input := `let größe = "héllo wörld";
+	let 名前 = "\u{1F600} \x41\u{e9}";
+	नमस्ते + _x;
+	€`
+	tests := []struct {
+		expectedType    token.Type
+		expectedLiteral string
+	}{
+		{token.LET, "let"},
+		{token.IDENT, "größe"},
+		{token.ASSIGN, "="},
+		{token.STRING, "héllo wörld"},
+		{token.SEMICOLON, ";"},
+
+		{token.LET, "let"},
+		{token.IDENT, "名前"},
+		{token.ASSIGN, "="},
+		{token.STRING, "😀 Aé"},
+		{token.SEMICOLON, ";"},
+
+		{token.IDENT, "नमस्ते"},
+		{token.PLUS, "+"},
+		{token.IDENT, "_x"},
+		{token.SEMICOLON, ";"},
+
+		{token.ILLEGAL, "€"},
+		{token.EOF, ""},
+	}
+
+	l := NewLexer(input)
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestUnicodeColumns(t *testing.T) {
+	l := NewLexer(`"日本" + x`)
+	l.NextToken()
+	plus := l.NextToken()
+	if plus.Pos.Column != 6 || plus.Pos.Offset != 9 {
+		t.Fatalf("wrong position for '+'. expected column 6 offset 9, got=%+v", plus.Pos)
+	}
+}
+
+func TestStringEscapes(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedType    token.Type
+		expectedLiteral string
+		expectedError   string
+	}{
+		{`"a\\"`, token.STRING, `a\`, ""},
+		{`"\\n"`, token.STRING, `\n`, ""},
+		{`"tab\there"`, token.STRING, "tab\there", ""},
+		{`"nul\0"`, token.STRING, "nul\x00", ""},
+		{`"\r\n"`, token.STRING, "\r\n", ""},
+		{`"\x41\x7a"`, token.STRING, "Az", ""},
+		{`"\u{48}\u{1F600}"`, token.STRING, "H😀", ""},
+		{`"\q"`, token.ILLEGAL, "", `unknown escape sequence "\\q"`},
+		{`"\x4"`, token.ILLEGAL, "", `invalid escape sequence \x: expected 2 hex digits`},
+		{`"\u48"`, token.ILLEGAL, "", `invalid escape sequence \u: expected \u{...}`},
+		{`"\u{}"`, token.ILLEGAL, "", `invalid escape sequence \u{...}: expected 1 to 6 hex digits`},
+		{`"\u{D800}"`, token.ILLEGAL, "", `invalid escape sequence \u{D800}: not a valid code point`},
+		{`"never closed`, token.ILLEGAL, "never closed", "unterminated string literal"},
+		{`"ends with \`, token.ILLEGAL, "ends with ", "unterminated string literal"},
+	}
+
+	for i, tt := range tests {
+		l := NewLexer(tt.input)
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+		if tt.expectedType == token.STRING && tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+		if tok.Error != tt.expectedError {
+			t.Fatalf("tests[%d] - error wrong. expected=%q, got=%q",
+				i, tt.expectedError, tok.Error)
+		}
+		if next := l.NextToken(); next.Type != token.EOF {
+			t.Fatalf("tests[%d] - expected EOF after string. got=%q", i, next.Type)
+		}
+	}
+}
+
+func TestRawAndMultilineStrings(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedType    token.Type
+		expectedLiteral string
+	}{
+		{"`C:\\path\\n`", token.STRING, `C:\path\n`},
+		{"`SELECT *\n  FROM \"users\"`", token.STRING, "SELECT *\n  FROM \"users\""},
+		{`""`, token.STRING, ""},
+		{`""""""`, token.STRING, ""},
+		{`"""say "hi" \n"""`, token.STRING, `say "hi" \n`},
+		{"\"\"\"\n\t\t{\n\t\t  \"a\": 1\n\n\t\t}\n\t\t\"\"\"", token.STRING, "{\n  \"a\": 1\n\n}"},
+		{"\"\"\"first\n    second\n      third\"\"\"", token.STRING, "first\nsecond\n  third"},
+		{"`never closed", token.ILLEGAL, "never closed"},
+		{`"""never closed""`, token.ILLEGAL, `never closed""`},
+	}
+
+	for i, tt := range tests {
+		l := NewLexer(tt.input)
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+		if next := l.NextToken(); next.Type != token.EOF {
+			t.Fatalf("tests[%d] - expected EOF after string. got=%q", i, next.Type)
+		}
+	}
+}
+
+func TestInterpolatedStringTokens(t *testing.T) {
+	input := `"total: ${a + b} items" "${ {"k": "${x}"}["k"] }!" "cost: \${5} $x"`
+	tests := []struct {
+		expectedType    token.Type
+		expectedLiteral string
+	}{
+		{token.STRINGSTART, "total: "},
+		{token.IDENT, "a"},
+		{token.PLUS, "+"},
+		{token.IDENT, "b"},
+		{token.STRINGEND, " items"},
+
+		{token.STRINGSTART, ""},
+		{token.LBRACE, "{"},
+		{token.STRING, "k"},
+		{token.COLON, ":"},
+		{token.STRINGSTART, ""},
+		{token.IDENT, "x"},
+		{token.STRINGEND, ""},
+		{token.RBRACE, "}"},
+		{token.LBRACKET, "["},
+		{token.STRING, "k"},
+		{token.RBRACKET, "]"},
+		{token.STRINGEND, "!"},
+
+		{token.STRING, "cost: ${5} $x"},
+		{token.EOF, ""},
+	}
+
+	l := NewLexer(input)
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestReaderLexer(t *testing.T) {
+	input := `let größe = fn(x) { x * 1.5e3 }; // size
+	/* block */ let s = "héllo ${größe(2)} \u{1F600}";
+	let raw = ` + "`" + `a\nb` + "`" + `;
+	s != raw;`
+
+	expected := NewFileLexer("main.mk", input)
+	streamed := NewReaderLexer("main.mk", iotest.OneByteReader(strings.NewReader(input)))
+	for i := 0; ; i++ {
+		want := expected.NextToken()
+		got := streamed.NextToken()
+		if got.Type != want.Type || got.Literal != want.Literal {
+			t.Fatalf("tokens[%d] - wrong token. expected=%q %q, got=%q %q",
+				i, want.Type, want.Literal, got.Type, got.Literal)
+		}
+		if got.Pos != want.Pos || got.End != want.End {
+			t.Fatalf("tokens[%d] - wrong position. expected=%s-%s, got=%s-%s",
+				i, want.Pos, want.End, got.Pos, got.End)
+		}
+		if len(got.Comments) != len(want.Comments) || len(got.Trailing) != len(want.Trailing) {
+			t.Fatalf("tokens[%d] - wrong comments. expected=%+v %+v, got=%+v %+v",
+				i, want.Comments, want.Trailing, got.Comments, got.Trailing)
+		}
+		if want.Type == token.EOF {
+			break
+		}
+	}
+}
+
+// failingReader returns its input and then an error other than io.EOF
+type failingReader struct {
+	r io.Reader
+}
+
+func (fr *failingReader) Read(p []byte) (int, error) {
+	n, err := fr.r.Read(p)
+	if err == io.EOF {
+		return n, errors.New("connection reset")
+	}
+	return n, err
+}
+
+func TestReaderLexerError(t *testing.T) {
+	l := NewReaderLexer("", &failingReader{r: strings.NewReader("x;")})
+	for _, expected := range []token.Type{token.IDENT, token.SEMICOLON, token.ILLEGAL, token.EOF} {
+		tok := l.NextToken()
+		if tok.Type != expected {
+			t.Fatalf("tokentype wrong. expected=%q, got=%q", expected, tok.Type)
+		}
+		if tok.Type == token.ILLEGAL && tok.Error != "read error: connection reset" {
+			t.Fatalf("error wrong. got=%q", tok.Error)
+		}
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	tokens := Tokenize("let x = 1; // one")
+	expected := []token.Type{token.LET, token.IDENT, token.ASSIGN, token.INT, token.SEMICOLON, token.EOF}
+	if len(tokens) != len(expected) {
+		t.Fatalf("wrong number of tokens. expected=%d, got=%d", len(expected), len(tokens))
+	}
+	for i, tt := range expected {
+		if tokens[i].Type != tt {
+			t.Errorf("tokens[%d] - tokentype wrong. expected=%q, got=%q", i, tt, tokens[i].Type)
+		}
+	}
+	if len(tokens[4].Trailing) != 1 {
+		t.Errorf("the semicolon should hold the trailing comment. got=%+v", tokens[4].Trailing)
+	}
+}
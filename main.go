@@ -8,6 +8,15 @@ import (
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "tokens":
+			os.Exit(tokensCommand(os.Args[2:], os.Stdin, os.Stdout, os.Stderr))
+		case "run":
+			os.Exit(runCommand(os.Args[2:], os.Stdout, os.Stderr))
+		}
+	}
+
 	user, err := user.Current()
 	if err != nil {
 		panic(err)
@@ -30,6 +30,19 @@ func (e *Environment) Get(name string) (Object, bool) {
 	return obj, ok
 }
 
+// Assign updates the value of an existing binding, in the environment that defines it.
+// It reports false if no enclosing environment defines the name
+func (e *Environment) Assign(name string, val Object) (Object, bool) {
+	if _, ok := e.store[name]; ok {
+		e.store[name] = val
+		return val, true
+	}
+	if e.outer != nil {
+		return e.outer.Assign(name, val)
+	}
+	return nil, false
+}
+
 // Set associates the value with the given environment key (name) in the environment
 func (e *Environment) Set(name string, val Object) Object {
 	e.store[name] = val
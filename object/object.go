@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"hash/fnv"
 	"monkey/ast"
+	"monkey/token"
+	"sort"
 	"strconv"
 	"strings"
 )
@@ -28,6 +30,10 @@ const (
 	NANOBJ = "NAN"
 	// RETURNVALUEOBJ represents a return object
 	RETURNVALUEOBJ = "RETURN_VALUE"
+	// BREAKOBJ represents a break out of a loop
+	BREAKOBJ = "BREAK"
+	// CONTINUEOBJ represents a skip to the next iteration of a loop
+	CONTINUEOBJ = "CONTINUE"
 	// ERROROBJ represents an error object
 	ERROROBJ = "ERROR"
 	// IDENTIFIEROBJ represents an identifier object
@@ -131,7 +137,8 @@ func (b *Boolean) HashKey() HashKey {
 	return HashKey{Type: b.Type(), Value: value}
 }
 
-// Array the array data structure
+// Array the array data structure. Arrays are mutable and shared, not copied:
+// assigning to an element of an array changes it for every variable holding it
 type Array struct {
 	Elements Objects
 }
@@ -164,7 +171,7 @@ type HashPair struct {
 	Value Object
 }
 
-// Hash represents a hash object... {k:v}
+// Hash represents a hash object... {k:v}. Like arrays, hashes are mutable and shared
 type Hash struct {
 	Pairs map[HashKey]HashPair
 }
@@ -186,6 +193,60 @@ func (h *Hash) Inspect() string {
 	return out.String()
 }
 
+// SortedPairs returns the pairs of the hash in a stable order: booleans first, then
+// numbers, then strings, each sorted by value
+func (h *Hash) SortedPairs() []HashPair {
+	pairs := make([]HashPair, 0, len(h.Pairs))
+	for _, pair := range h.Pairs {
+		pairs = append(pairs, pair)
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		return lessKey(pairs[i].Key, pairs[j].Key)
+	})
+	return pairs
+}
+
+// lessKey reports whether the hash key a sorts before the hash key b
+func lessKey(a, b Object) bool {
+	if ra, rb := keyRank(a), keyRank(b); ra != rb {
+		return ra < rb
+	}
+	switch a := a.(type) {
+	case *Boolean:
+		return !a.Value && b.(*Boolean).Value
+	case *String:
+		return a.Value < b.(*String).Value
+	default:
+		if na, nb := keyNumber(a), keyNumber(b); na != nb {
+			return na < nb
+		}
+		// an integer and a double of the same value are distinct keys
+		_, aInt := a.(*Integer)
+		_, bInt := b.(*Integer)
+		return aInt && !bInt
+	}
+}
+
+// keyRank returns the rank of the type of a hash key in the order of SortedPairs
+func keyRank(key Object) int {
+	switch key.(type) {
+	case *Boolean:
+		return 0
+	case *Integer, *Double:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// keyNumber returns the value of a number hash key
+func keyNumber(key Object) float64 {
+	if d, ok := key.(*Double); ok {
+		return d.Value
+	}
+	return float64(key.(*Integer).Value)
+}
+
 // Null the nil type
 type Null struct{}
 
@@ -215,16 +276,40 @@ func (rv *ReturnValue) Type() Type { return RETURNVALUEOBJ }
 // Inspect returns a readable string of the return value
 func (rv *ReturnValue) Inspect() string { return rv.Value.Inspect() }
 
+// Break represents a break out of the enclosing loop
+type Break struct{}
+
+// Type returns the object type of this value
+func (b *Break) Type() Type { return BREAKOBJ }
+
+// Inspect returns a readable string of the break
+func (b *Break) Inspect() string { return "break" }
+
+// Continue represents a skip to the next iteration of the enclosing loop
+type Continue struct{}
+
+// Type returns the object type of this value
+func (c *Continue) Type() Type { return CONTINUEOBJ }
+
+// Inspect returns a readable string of the continue
+func (c *Continue) Inspect() string { return "continue" }
+
 // Error represents an error in our program
 type Error struct {
 	Message string
+	Pos     token.Position // position of the node that caused the error
 }
 
 // Type returns the object type of this value
 func (e *Error) Type() Type { return ERROROBJ }
 
 // Inspect returns a readable string of the error
-func (e *Error) Inspect() string { return "ERROR: " + e.Message }
+func (e *Error) Inspect() string {
+	if e.Pos.IsValid() {
+		return "ERROR: " + e.Pos.String() + ": " + e.Message
+	}
+	return "ERROR: " + e.Message
+}
 
 // Identifier the int type
 type Identifier struct {
@@ -241,6 +326,8 @@ func (i *Identifier) Inspect() string { return i.Value.Inspect() }
 // Function represents a function in our program
 type Function struct {
 	Parameters ast.Identifiers
+	Defaults   map[string]ast.Expression // evaluated in Env when their argument is missing
+	Rest       *ast.Identifier           // collects the remaining arguments into an Array
 	Body       *ast.BlockStatement
 	Env        *Environment
 }
@@ -251,13 +338,9 @@ func (f *Function) Type() Type { return FUNCTIONOBJ }
 // Inspect returns a readable string of the function
 func (f *Function) Inspect() string {
 	var out bytes.Buffer
-	params := []string{}
-	for _, p := range f.Parameters {
-		params = append(params, p.String())
-	}
 	out.WriteString("fn")
 	out.WriteString(" (")
-	out.WriteString(strings.Join(params, ", "))
+	out.WriteString(ast.ParametersString(f.Parameters, f.Defaults, f.Rest))
 	out.WriteString(") {\n")
 	for _, s := range f.Body.Statements {
 		out.WriteString(ast.TAB + s.String() + "\n")
@@ -38,3 +38,53 @@ func TestStringHashKey(t *testing.T) {
 		t.Errorf("strings with same content have different hash keys")
 	}
 }
+
+func TestHashSortedPairs(t *testing.T) {
+	keys := []Hashable{
+		&String{Value: "b"},
+		&Integer{Value: 10},
+		&Boolean{Value: true},
+		&String{Value: "a"},
+		&Double{Value: 2.5, Precision: 1},
+		&Integer{Value: -1},
+		&Boolean{Value: false},
+		&Double{Value: 10, Precision: 1},
+	}
+	hash := &Hash{Pairs: map[HashKey]HashPair{}}
+	for _, key := range keys {
+		hash.Pairs[key.HashKey()] = HashPair{Key: key.(Object), Value: &Null{}}
+	}
+
+	expected := []string{"false", "true", "-1", "2.5", "10", "10.0", "a", "b"}
+	pairs := hash.SortedPairs()
+	if len(pairs) != len(expected) {
+		t.Fatalf("wrong number of pairs. expected=%d, got=%d", len(expected), len(pairs))
+	}
+	for i, key := range expected {
+		if pairs[i].Key.Inspect() != key {
+			t.Errorf("pairs[%d] has wrong key. expected=%q, got=%q", i, key, pairs[i].Key.Inspect())
+		}
+	}
+}
+
+func TestEnvironmentAssign(t *testing.T) {
+	outer := NewEnvironment()
+	outer.Set("count", &Integer{Value: 1})
+	inner := NewEnclosedEnvironment(outer)
+
+	if _, ok := inner.Assign("count", &Integer{Value: 2}); !ok {
+		t.Fatalf("Assign did not find the binding of the outer environment")
+	}
+	if _, ok := inner.store["count"]; ok {
+		t.Errorf("Assign created a binding in the inner environment")
+	}
+	if val, _ := outer.Get("count"); val.(*Integer).Value != 2 {
+		t.Errorf("outer binding not updated. got=%s", val.Inspect())
+	}
+	if _, ok := inner.Assign("missing", &Integer{Value: 3}); ok {
+		t.Errorf("Assign reported a binding for an undefined name")
+	}
+	if _, ok := inner.Get("missing"); ok {
+		t.Errorf("Assign created a binding for an undefined name")
+	}
+}
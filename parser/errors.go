@@ -0,0 +1,92 @@
+package parser
+
+import (
+	"bytes"
+	"fmt"
+	"monkey/token"
+	"strings"
+)
+
+// ErrorCode identifies the kind of a parse error
+type ErrorCode string
+
+const (
+	// UnexpectedToken a token other than the expected one was found
+	UnexpectedToken ErrorCode = "unexpected-token"
+	// MissingExpression no expression can start with the found token
+	MissingExpression ErrorCode = "missing-expression"
+	// IllegalToken the lexer could not make sense of the input
+	IllegalToken ErrorCode = "illegal-token"
+	// InvalidNumber a number literal can't be represented
+	InvalidNumber ErrorCode = "invalid-number"
+	// InvalidAssignment the left side of an assignment can't be assigned to
+	InvalidAssignment ErrorCode = "invalid-assignment"
+	// OutsideLoop a break or continue is not inside a loop
+	OutsideLoop ErrorCode = "outside-loop"
+)
+
+// ParseError describes a syntax error found by the parser
+type ParseError struct {
+	Code     ErrorCode
+	Message  string
+	Pos      token.Position // position of the first char of the offending token
+	End      token.Position // position immediately after the last char of the offending token
+	Expected []token.Type   // the token types that would have been valid here, if known
+	Found    token.Token    // the offending token
+}
+
+// Error returns the error message prefixed with its position
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Pos, e.Message)
+}
+
+// Render returns the error formatted compiler-style: its position, code and message,
+// followed by the offending line of the source with the offending token underlined
+func (e *ParseError) Render(source string) string {
+	var out bytes.Buffer
+	out.WriteString(fmt.Sprintf("%s: error[%s]: %s\n", e.Pos, e.Code, e.Message))
+	out.WriteString(Snippet(source, e.Pos, e.End))
+	return out.String()
+}
+
+// Snippet returns the source line holding pos, numbered, with a caret underline running
+// from pos to end, or to the end of the line if end is on a later line:
+//
+//	2 | let = 10;
+//	  |     ^
+//
+// It returns an empty string if pos is not in the source
+func Snippet(source string, pos token.Position, end token.Position) string {
+	lines := strings.Split(source, "\n")
+	if !pos.IsValid() || pos.Line > len(lines) {
+		return ""
+	}
+	line := []rune(strings.TrimRight(lines[pos.Line-1], "\r"))
+
+	start := pos.Column - 1
+	if start > len(line) {
+		start = len(line)
+	}
+	stop := len(line)
+	if end.Line == pos.Line && end.Column-1 < stop {
+		stop = end.Column - 1
+	}
+
+	// keep the tabs of the line so that the underline stays aligned
+	var underline bytes.Buffer
+	for _, ch := range line[:start] {
+		if ch == '\t' {
+			underline.WriteRune('\t')
+		} else {
+			underline.WriteRune(' ')
+		}
+	}
+	underline.WriteString("^")
+	if stop-start > 1 {
+		underline.WriteString(strings.Repeat("~", stop-start-1))
+	}
+
+	number := fmt.Sprintf("%d", pos.Line)
+	gutter := strings.Repeat(" ", len(number))
+	return fmt.Sprintf(" %s | %s\n %s | %s\n", number, string(line), gutter, underline.String())
+}
@@ -5,7 +5,9 @@ import (
 	"monkey/ast"
 	"monkey/lexer"
 	"monkey/token"
+	"monkey/utils"
 	"strconv"
+	"strings"
 )
 
 // Here we use iota to give the following constants incrementing numbers as values,
@@ -14,35 +16,50 @@ const (
 	_ int = iota
 	// LOWEST precedence
 	LOWEST
-	// EQUALS just above lowest in prcecedence
+	// ASSIGN just above lowest in prcecedence
+	ASSIGN // = += -= *= /=
+	// CONDITIONAL just above assign in prcecedence
+	CONDITIONAL // ?:
+	// NULLISH just above conditional in prcecedence
+	NULLISH // ??
+	// LOGICALOR just above nullish in prcecedence
+	LOGICALOR // ||
+	// LOGICALAND just above logical or in prcecedence
+	LOGICALAND // &&
+	// EQUALS just above logical and in prcecedence
 	EQUALS // ==
 	// LESSGREATER just above equals in prcecedence
 	LESSGREATER // > or <
 	// LESSGREATEREQUALS just above lowest in prcecedence
 	LESSGREATEREQUALS // <= >=
 	// SUM just above less than or greater than in prcecedence
-	SUM // +
+	SUM // + - | xor
 	// PRODUCT just above sum in prcecedence
-	PRODUCT // *
+	PRODUCT // * / % & << >>
 	// POWER just above product in prcecedence
 	POWER // ^
 	// PREFIX just above power in prcecedence
-	PREFIX // -X or !X
-	// PERIOD just above prefix in prcecedence
-	PERIOD // 1.2
-	// CALL just above prefix in prcecedence
+	PREFIX // -X or !X or ~X
+	// POSTFIX just above prefix in prcecedence
+	POSTFIX // X++ or X--
+	// CALL just above postfix in prcecedence
 	CALL // myFunction(X)
 	// INDEX above all others in prcecedence
-	INDEX // array[index]
+	INDEX // array[index] or hash.key
 )
 
 // precedence table: it associates token types with their precedence
 // () [] -> . :: ! ~ & ++ -- * / % + - << >> < <= > >= == != & ^ | && || ?: = += -= *= /= %= &= |= ^= <<= >>= ,
 var precedences = map[token.Type]int{
-	token.PLUSEQ:     EQUALS,
-	token.MINUSEQ:    EQUALS,
-	token.SLASHEQ:    EQUALS,
-	token.ASTERISKEQ: EQUALS,
+	token.ASSIGN:     ASSIGN,
+	token.PLUSEQ:     ASSIGN,
+	token.MINUSEQ:    ASSIGN,
+	token.SLASHEQ:    ASSIGN,
+	token.ASTERISKEQ: ASSIGN,
+	token.QUESTION:   CONDITIONAL,
+	token.NULLISH:    NULLISH,
+	token.OR:         LOGICALOR,
+	token.AND:        LOGICALAND,
 	token.EQ:         EQUALS,
 	token.NOTEQ:      EQUALS,
 	token.LT:         LESSGREATER,
@@ -51,13 +68,20 @@ var precedences = map[token.Type]int{
 	token.GTEQ:       LESSGREATEREQUALS,
 	token.PLUS:       SUM,
 	token.MINUS:      SUM,
+	token.BITOR:      SUM,
+	token.XOR:        SUM,
 	token.SLASH:      PRODUCT,
 	token.ASTERISK:   PRODUCT,
 	token.MODULUS:    PRODUCT,
+	token.BITAND:     PRODUCT,
+	token.SHL:        PRODUCT,
+	token.SHR:        PRODUCT,
 	token.POWER:      POWER,
-	token.PERIOD:     PERIOD,
+	token.INCREMENT:  POSTFIX,
+	token.DECREMENT:  POSTFIX,
 	token.LPAREN:     CALL,
 	token.LBRACKET:   INDEX,
+	token.PERIOD:     INDEX,
 }
 
 type (
@@ -70,20 +94,27 @@ type Parser struct {
 	l              *lexer.Lexer
 	curToken       token.Token
 	peekToken      token.Token
-	errors         []string
+	errors         []*ParseError
+	comments       []token.Comment
+	attached       map[ast.Statement]*ast.StatementComments
+	panicking      bool // an error was reported in the current statement
+	depth          int  // number of braces left open before curToken
+	loops          int  // number of loops enclosing curToken within the current function
 	prefixParseFns map[token.Type]prefixParseFn
 	infixParseFns  map[token.Type]infixParseFn
 }
 
 // NewParser given a lexer, creates and returns a new parser
 func NewParser(l *lexer.Lexer) *Parser {
-	p := &Parser{l: l, errors: []string{}}
+	p := &Parser{l: l, errors: []*ParseError{}, attached: map[ast.Statement]*ast.StatementComments{}}
 
 	// register prefix parse function for all of our prefix operators
 	p.prefixParseFns = make(map[token.Type]prefixParseFn)
 	p.registerPrefix(token.IDENT, p.parseIdentifier)
 	p.registerPrefix(token.INT, p.parseIntegerLiteral)
+	p.registerPrefix(token.DOUBLE, p.parseDoubleLiteral)
 	p.registerPrefix(token.STRING, p.parseStringLiteral)
+	p.registerPrefix(token.STRINGSTART, p.parseInterpolatedString)
 
 	// register array literal parser
 	p.registerPrefix(token.LBRACKET, p.parseArrayLiteral)
@@ -92,6 +123,9 @@ func NewParser(l *lexer.Lexer) *Parser {
 
 	p.registerPrefix(token.BANG, p.parsePrefixExpression)
 	p.registerPrefix(token.MINUS, p.parsePrefixExpression)
+	p.registerPrefix(token.BITNOT, p.parsePrefixExpression)
+	p.registerPrefix(token.INCREMENT, p.parsePrefixUpdateExpression)
+	p.registerPrefix(token.DECREMENT, p.parsePrefixUpdateExpression)
 
 	// register boolean parser
 	p.registerPrefix(token.TRUE, p.parseBoolean)
@@ -103,9 +137,16 @@ func NewParser(l *lexer.Lexer) *Parser {
 	// register conditional if...else parser
 	p.registerPrefix(token.IF, p.parseIfExpression)
 
+	// register loop parsers
+	p.registerPrefix(token.WHILE, p.parseWhileExpression)
+	p.registerPrefix(token.FOR, p.parseForExpression)
+
 	// register function (fn) parser
 	p.registerPrefix(token.FUNCTION, p.parseFunctionLiteral)
 
+	// report the lexer's diagnostics for malformed input
+	p.registerPrefix(token.ILLEGAL, p.parseIllegal)
+
 	// register infix parse function for all of our infix operators
 	p.infixParseFns = make(map[token.Type]infixParseFn)
 	p.registerInfix(token.PLUS, p.parseInfixExpression)
@@ -113,13 +154,25 @@ func NewParser(l *lexer.Lexer) *Parser {
 	p.registerInfix(token.SLASH, p.parseInfixExpression)
 	p.registerInfix(token.ASTERISK, p.parseInfixExpression)
 	p.registerInfix(token.POWER, p.parseInfixExpression)
-	p.registerInfix(token.PERIOD, p.parseInfixExpression)
 	p.registerInfix(token.MODULUS, p.parseInfixExpression)
 
-	p.registerInfix(token.PLUSEQ, p.parseInfixExpression)
-	p.registerInfix(token.MINUSEQ, p.parseInfixExpression)
-	p.registerInfix(token.SLASHEQ, p.parseInfixExpression)
-	p.registerInfix(token.ASTERISKEQ, p.parseInfixExpression)
+	p.registerInfix(token.ASSIGN, p.parseAssignExpression)
+	p.registerInfix(token.PLUSEQ, p.parseAssignExpression)
+	p.registerInfix(token.MINUSEQ, p.parseAssignExpression)
+	p.registerInfix(token.SLASHEQ, p.parseAssignExpression)
+	p.registerInfix(token.ASTERISKEQ, p.parseAssignExpression)
+
+	p.registerInfix(token.BITAND, p.parseInfixExpression)
+	p.registerInfix(token.BITOR, p.parseInfixExpression)
+	p.registerInfix(token.XOR, p.parseInfixExpression)
+	p.registerInfix(token.SHL, p.parseInfixExpression)
+	p.registerInfix(token.SHR, p.parseInfixExpression)
+
+	p.registerInfix(token.QUESTION, p.parseConditionalExpression)
+
+	p.registerInfix(token.AND, p.parseInfixExpression)
+	p.registerInfix(token.OR, p.parseInfixExpression)
+	p.registerInfix(token.NULLISH, p.parseInfixExpression)
 
 	p.registerInfix(token.EQ, p.parseInfixExpression)
 	p.registerInfix(token.NOTEQ, p.parseInfixExpression)
@@ -129,8 +182,12 @@ func NewParser(l *lexer.Lexer) *Parser {
 	p.registerInfix(token.LT, p.parseInfixExpression)
 	p.registerInfix(token.GT, p.parseInfixExpression)
 
+	p.registerInfix(token.INCREMENT, p.parsePostfixUpdateExpression)
+	p.registerInfix(token.DECREMENT, p.parsePostfixUpdateExpression)
+
 	p.registerInfix(token.LPAREN, p.parseCallExpression)
 	p.registerInfix(token.LBRACKET, p.parseIndexExpression)
+	p.registerInfix(token.PERIOD, p.parseMemberExpression)
 
 	// Read two tokens, so curToken and peekToken are both set
 	p.nextToken()
@@ -138,10 +195,20 @@ func NewParser(l *lexer.Lexer) *Parser {
 	return p
 }
 
-// nextToken advance to the next token
+// nextToken advance to the next token, keeping aside the comments attached to it
 func (p *Parser) nextToken() {
+	switch p.curToken.Type {
+	case token.LBRACE:
+		p.depth++
+	case token.RBRACE:
+		if p.depth > 0 {
+			p.depth--
+		}
+	}
 	p.curToken = p.peekToken
 	p.peekToken = p.l.NextToken()
+	p.comments = append(p.comments, p.peekToken.Comments...)
+	p.comments = append(p.comments, p.peekToken.Trailing...)
 }
 
 // curTokenIs check that the current token type matches t
@@ -186,10 +253,27 @@ func (p *Parser) registerPrefix(tokenType token.Type, fn prefixParseFn) {
 	p.prefixParseFns[tokenType] = fn
 }
 
+// addError records a parse error about the found token. Only the first error of a
+// statement is recorded, the rest are usually follow-on errors of the same mistake
+func (p *Parser) addError(code ErrorCode, found token.Token, expected []token.Type, msg string) {
+	if p.panicking {
+		return
+	}
+	p.panicking = true
+	p.errors = append(p.errors, &ParseError{
+		Code:     code,
+		Message:  msg,
+		Pos:      found.Pos,
+		End:      found.End,
+		Expected: expected,
+		Found:    found,
+	})
+}
+
 // noPrefixParseFnError sets the error for a prefix expression that has no registered prefix parser
 func (p *Parser) noPrefixParseFnError(t token.Type) {
 	msg := fmt.Sprintf("no prefix parse function for %s found", t)
-	p.errors = append(p.errors, msg)
+	p.addError(MissingExpression, p.curToken, nil, msg)
 }
 
 // registerInfix registers an infix parser for a token type
@@ -197,14 +281,17 @@ func (p *Parser) registerInfix(tokenType token.Type, fn infixParseFn) {
 	p.infixParseFns[tokenType] = fn
 }
 
-// noInfixParseFnError sets the error for an infix expression that has no registered infix parser
-func (p *Parser) noInfixParseFnError(t token.Type, left string, right string) {
-	msg := fmt.Sprintf("no infix parse function for %s %s %s found", left, t, right)
-	p.errors = append(p.errors, msg)
+// Errors returns the list of error messages, prefixed with their positions
+func (p *Parser) Errors() []string {
+	messages := []string{}
+	for _, err := range p.errors {
+		messages = append(messages, err.Error())
+	}
+	return messages
 }
 
-// Errors returns the list of errors
-func (p *Parser) Errors() []string {
+// ParseErrors returns the list of errors as structured data
+func (p *Parser) ParseErrors() []*ParseError {
 	return p.errors
 }
 
@@ -213,7 +300,25 @@ func (p *Parser) Errors() []string {
 func (p *Parser) peekError(t token.Type) {
 	msg := fmt.Sprintf("expected next token to be %s, got %s instead",
 		t, p.peekToken.Type)
-	p.errors = append(p.errors, msg)
+	p.addError(UnexpectedToken, p.peekToken, []token.Type{t}, msg)
+}
+
+// synchronize skips the tokens of a statement holding an error, up to the start of
+// the next statement at the given brace depth: after a semicolon, or at a let,
+// a return or the closing brace of the enclosing block
+func (p *Parser) synchronize(depth int) {
+	for {
+		semicolon := p.curTokenIs(token.SEMICOLON) && p.depth == depth
+		p.nextToken()
+		if p.curTokenIs(token.EOF) {
+			return
+		}
+		if semicolon || (p.depth == depth &&
+			(p.curTokenIs(token.LET) || p.curTokenIs(token.RETURN) || p.curTokenIs(token.RBRACE))) {
+			p.panicking = false
+			return
+		}
+	}
 }
 
 // parseIdentifier parses the current token as an identifier
@@ -221,28 +326,113 @@ func (p *Parser) parseIdentifier() ast.Expression {
 	return &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
 }
 
+// parseIllegal reports the error attached to an ILLEGAL token by the lexer
+func (p *Parser) parseIllegal() ast.Expression {
+	msg := p.curToken.Error
+	if msg == "" {
+		msg = fmt.Sprintf("illegal token %q", p.curToken.Literal)
+	}
+	p.addError(IllegalToken, p.curToken, nil, msg)
+	return nil
+}
+
 // parseIntegerLiteral parses the current token as an integer literal
 func (p *Parser) parseIntegerLiteral() ast.Expression {
 	// defer untrace(trace("parseIntegerLiteral"))
 	lit := &ast.IntegerLiteral{Token: p.curToken}
-	value, err := strconv.ParseInt(p.curToken.Literal, 10, 64)
+	digits := strings.ReplaceAll(p.curToken.Literal, "_", "")
+	base := 10
+	if len(digits) > 2 && digits[0] == '0' {
+		switch digits[1] {
+		case 'x', 'X':
+			base = 16
+		case 'o', 'O':
+			base = 8
+		case 'b', 'B':
+			base = 2
+		}
+		if base != 10 {
+			digits = digits[2:]
+		}
+	}
+	value, err := strconv.ParseInt(digits, base, 64)
 	if err != nil {
 		msg := fmt.Sprintf("could not parse %q as integer", p.curToken.Literal)
-		p.errors = append(p.errors, msg)
+		p.addError(InvalidNumber, p.curToken, nil, msg)
 		return nil
 	}
 	lit.Value = value
 	return lit
 }
 
+// parseDoubleLiteral parses the current token as a double literal
+func (p *Parser) parseDoubleLiteral() ast.Expression {
+	lit := &ast.DoubleLiteral{Token: p.curToken}
+	literal := strings.ReplaceAll(p.curToken.Literal, "_", "")
+	value, err := strconv.ParseFloat(literal, 64)
+	if err != nil {
+		msg := fmt.Sprintf("could not parse %q as double", p.curToken.Literal)
+		p.addError(InvalidNumber, p.curToken, nil, msg)
+		return nil
+	}
+	lit.Value = value
+	lit.Precision = doublePrecision(literal)
+	return lit
+}
+
+// doublePrecision returns the number of decimal places needed to display the double
+// literal: the digits of its fraction, shifted by its exponent
+func doublePrecision(literal string) int {
+	mantissa, exponent := literal, 0
+	if i := strings.IndexAny(literal, "eE"); i >= 0 {
+		mantissa = literal[:i]
+		exponent, _ = strconv.Atoi(literal[i+1:])
+	}
+	precision := utils.Precision(mantissa) - exponent
+	if precision < 0 {
+		return 0
+	}
+	return precision
+}
+
 func (p *Parser) parseStringLiteral() ast.Expression {
 	// defer untrace(trace("parseStringLiteral"))
 	return &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal}
 }
 
+// parseInterpolatedString parses the string parts and embedded expressions
+// from a STRINGSTART token up to the matching STRINGEND token
+func (p *Parser) parseInterpolatedString() ast.Expression {
+	str := &ast.InterpolatedString{Token: p.curToken}
+	str.Parts = ast.Expressions{&ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal}}
+	for {
+		p.nextToken()
+		part := p.parseExpression(LOWEST)
+		if part == nil {
+			return nil
+		}
+		str.Parts = append(str.Parts, part)
+		if !p.peekTokenIs(token.STRINGMIDDLE) {
+			break
+		}
+		p.nextToken()
+		str.Parts = append(str.Parts, &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal})
+	}
+	if !p.expectPeek(token.STRINGEND) {
+		return nil
+	}
+	str.Parts = append(str.Parts, &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal})
+	str.EndToken = p.curToken
+	return str
+}
+
 func (p *Parser) parseArrayLiteral() ast.Expression {
 	array := &ast.ArrayLiteral{Token: p.curToken}
 	array.Elements = p.parseExpressionList(token.RBRACKET)
+	if array.Elements == nil {
+		return nil
+	}
+	array.EndToken = p.curToken
 	return array
 }
 
@@ -251,13 +441,25 @@ func (p *Parser) parseHashLiteral() ast.Expression {
 	hash.Pairs = make(map[ast.Expression]ast.Expression)
 	for !p.peekTokenIs(token.RBRACE) {
 		p.nextToken()
-		key := p.parseExpression(LOWEST)
-		if !p.expectPeek(token.COLON) {
-			return nil
+		if p.curTokenIs(token.ELLIPSIS) {
+			spread := p.parseSpreadExpression()
+			if spread == nil {
+				return nil
+			}
+			hash.Order = append(hash.Order, spread)
+		} else {
+			key := p.parseExpression(LOWEST)
+			if key == nil || !p.expectPeek(token.COLON) {
+				return nil
+			}
+			p.nextToken()
+			value := p.parseExpression(LOWEST)
+			if value == nil {
+				return nil
+			}
+			hash.Pairs[key] = value
+			hash.Order = append(hash.Order, key)
 		}
-		p.nextToken()
-		value := p.parseExpression(LOWEST)
-		hash.Pairs[key] = value
 		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
 			return nil
 		}
@@ -265,16 +467,51 @@ func (p *Parser) parseHashLiteral() ast.Expression {
 	if !p.expectPeek(token.RBRACE) {
 		return nil
 	}
+	hash.EndToken = p.curToken
 	return hash
 }
 
+// parseIndexExpression parses an index expression, arr[i], or a slice expression,
+// arr[low:high], where both bounds are optional
 func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
 	exp := &ast.IndexExpression{Token: p.curToken, Left: left}
 	p.nextToken()
-	exp.Index = p.parseExpression(LOWEST)
+	if !p.curTokenIs(token.COLON) {
+		exp.Index = p.parseExpression(LOWEST)
+		if exp.Index == nil {
+			return nil
+		}
+		if !p.peekTokenIs(token.COLON) {
+			if !p.expectPeek(token.RBRACKET) {
+				return nil
+			}
+			exp.EndToken = p.curToken
+			return exp
+		}
+		p.nextToken()
+	}
+
+	slice := &ast.SliceExpression{Token: exp.Token, Left: left, Low: exp.Index}
+	if !p.peekTokenIs(token.RBRACKET) {
+		p.nextToken()
+		if slice.High = p.parseExpression(LOWEST); slice.High == nil {
+			return nil
+		}
+	}
 	if !p.expectPeek(token.RBRACKET) {
 		return nil
 	}
+	slice.EndToken = p.curToken
+	return slice
+}
+
+// parseMemberExpression parses a member access: user.name
+func (p *Parser) parseMemberExpression(object ast.Expression) ast.Expression {
+	exp := &ast.MemberExpression{Token: p.curToken, Object: object}
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+	exp.Member = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
 	return exp
 }
 
@@ -288,7 +525,7 @@ func (p *Parser) parseExpression(precedence int) ast.Expression {
 	}
 	leftExp := prefix()
 
-	for !p.peekTokenIs(token.SEMICOLON) && precedence < p.peekPrecedence() {
+	for leftExp != nil && !p.peekTokenIs(token.SEMICOLON) && precedence < p.peekPrecedence() {
 		infix := p.infixParseFns[p.peekToken.Type]
 		if infix == nil {
 			return leftExp
@@ -308,11 +545,18 @@ func (p *Parser) parseExpressionList(end token.Type) ast.Expressions {
 		return list
 	}
 	p.nextToken()
-	list = append(list, p.parseExpression(LOWEST))
+	item := p.parseListItem()
+	if item == nil {
+		return nil
+	}
+	list = append(list, item)
 	for p.peekTokenIs(token.COMMA) {
 		p.nextToken()
 		p.nextToken()
-		list = append(list, p.parseExpression(LOWEST))
+		if item = p.parseListItem(); item == nil {
+			return nil
+		}
+		list = append(list, item)
 	}
 
 	if !p.expectPeek(end) {
@@ -321,6 +565,28 @@ func (p *Parser) parseExpressionList(end token.Type) ast.Expressions {
 	return list
 }
 
+// parseListItem parses an element of an array literal or an argument of a call,
+// which may be spread
+func (p *Parser) parseListItem() ast.Expression {
+	if p.curTokenIs(token.ELLIPSIS) {
+		if spread := p.parseSpreadExpression(); spread != nil {
+			return spread
+		}
+		return nil
+	}
+	return p.parseExpression(LOWEST)
+}
+
+// parseSpreadExpression parses the value following a '...' token
+func (p *Parser) parseSpreadExpression() *ast.SpreadExpression {
+	spread := &ast.SpreadExpression{Token: p.curToken}
+	p.nextToken()
+	if spread.Value = p.parseExpression(LOWEST); spread.Value == nil {
+		return nil
+	}
+	return spread
+}
+
 // parsePrefixExpression parses the current token as a prefix expression
 func (p *Parser) parsePrefixExpression() ast.Expression {
 	// defer untrace(trace("parsePrefixExpression"))
@@ -330,6 +596,9 @@ func (p *Parser) parsePrefixExpression() ast.Expression {
 	}
 	p.nextToken()
 	expression.Right = p.parseExpression(PREFIX)
+	if expression.Right == nil {
+		return nil
+	}
 	return expression
 }
 
@@ -351,38 +620,98 @@ func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
 	// }
 
 	expression.Right = p.parseExpression(precedence)
+	if expression.Right == nil {
+		return nil
+	}
+	return expression
+}
 
-	if expression.Operator == token.PERIOD {
-		l, okl := expression.Left.(*ast.IntegerLiteral)
-		r, okr := expression.Right.(*ast.IntegerLiteral)
-		if !(okl && okr) {
-			p.noInfixParseFnError(token.PERIOD, expression.Left.TokenLiteral(), expression.Right.TokenLiteral())
-			return nil
-		}
-
-		literal := fmt.Sprintf("%s%s%s", l.TokenLiteral(), token.PERIOD, r.TokenLiteral())
-
-		precision := len(r.TokenLiteral())
+// parseAssignExpression parses an assignment to a variable or to an array or hash
+// element. Assignments are right associative: a = b = 5 assigns 5 to both a and b
+func (p *Parser) parseAssignExpression(target ast.Expression) ast.Expression {
+	expression := &ast.AssignExpression{
+		Token:    p.curToken,
+		Target:   target,
+		Operator: p.curToken.Literal,
+	}
+	if !p.checkAssignable(target, expression.Token, "assign to") {
+		return nil
+	}
+	p.nextToken()
+	if expression.Value = p.parseExpression(ASSIGN - 1); expression.Value == nil {
+		return nil
+	}
+	return expression
+}
 
-		double := &ast.DoubleLiteral{Token: token.Token{Literal: literal, Type: token.DOUBLE}, Precision: precision}
+// parseConditionalExpression parses a ternary conditional: cond ? a : b.
+// It is right associative: a ? b : c ? d : e is a ? b : (c ? d : e)
+func (p *Parser) parseConditionalExpression(condition ast.Expression) ast.Expression {
+	expression := &ast.ConditionalExpression{Token: p.curToken, Condition: condition}
+	p.nextToken()
+	expression.Consequence = p.parseExpression(LOWEST)
+	if expression.Consequence == nil || !p.expectPeek(token.COLON) {
+		return nil
+	}
+	p.nextToken()
+	if expression.Alternative = p.parseExpression(CONDITIONAL - 1); expression.Alternative == nil {
+		return nil
+	}
+	return expression
+}
 
-		value, err := strconv.ParseFloat(literal, 64)
-		if err != nil {
-			msg := fmt.Sprintf("could not parse %q as double", literal)
-			p.errors = append(p.errors, msg)
-			return nil
-		}
-		double.Value = value
+// parsePrefixUpdateExpression parses an increment or a decrement placed before
+// its target: ++x or --x
+func (p *Parser) parsePrefixUpdateExpression() ast.Expression {
+	expression := &ast.UpdateExpression{
+		Token:    p.curToken,
+		Operator: p.curToken.Literal,
+		Prefix:   true,
+	}
+	p.nextToken()
+	if expression.Target = p.parseExpression(PREFIX); expression.Target == nil {
+		return nil
+	}
+	if !p.checkAssignable(expression.Target, expression.Token, "apply "+expression.Operator+" to") {
+		return nil
+	}
+	return expression
+}
 
-		return double
+// parsePostfixUpdateExpression parses an increment or a decrement placed after
+// its target: x++ or x--
+func (p *Parser) parsePostfixUpdateExpression(target ast.Expression) ast.Expression {
+	expression := &ast.UpdateExpression{
+		Token:    p.curToken,
+		Operator: p.curToken.Literal,
+		Target:   target,
+	}
+	if !p.checkAssignable(target, expression.Token, "apply "+expression.Operator+" to") {
+		return nil
 	}
 	return expression
 }
 
+// checkAssignable reports whether the target is a variable or an array or hash element,
+// recording an error at the operator token otherwise
+func (p *Parser) checkAssignable(target ast.Expression, operator token.Token, action string) bool {
+	switch target.(type) {
+	case *ast.Identifier, *ast.IndexExpression, *ast.MemberExpression:
+		return true
+	}
+	msg := fmt.Sprintf("cannot %s %s", action, target.String())
+	p.addError(InvalidAssignment, operator, nil, msg)
+	return false
+}
+
 func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression {
 	// defer untrace(trace("parseCallExpression"))
 	exp := &ast.CallExpression{Token: p.curToken, Function: function}
 	exp.Arguments = p.parseExpressionList(token.RPAREN)
+	if exp.Arguments == nil {
+		return nil
+	}
+	exp.EndToken = p.curToken
 	return exp
 }
 
@@ -393,7 +722,7 @@ func (p *Parser) parseBoolean() ast.Expression {
 func (p *Parser) parseGroupedExpression() ast.Expression {
 	p.nextToken()
 	exp := p.parseExpression(LOWEST)
-	if !p.expectPeek(token.RPAREN) {
+	if exp == nil || !p.expectPeek(token.RPAREN) {
 		return nil
 	}
 	return exp
@@ -406,67 +735,202 @@ func (p *Parser) parseIfExpression() ast.Expression {
 	}
 	p.nextToken()
 	expression.Condition = p.parseExpression(LOWEST)
-	if !p.expectPeek(token.RPAREN) {
+	if expression.Condition == nil || !p.expectPeek(token.RPAREN) {
 		return nil
 	}
 	if !p.expectPeek(token.LBRACE) {
 		return nil
 	}
-	expression.Consequence = p.parseBlockStatement()
+	if expression.Consequence = p.parseBlockStatement(); expression.Consequence == nil {
+		return nil
+	}
 	if p.peekTokenIs(token.ELSE) {
 		p.nextToken()
 		if !p.expectPeek(token.LBRACE) {
 			return nil
 		}
-		expression.Alternative = p.parseBlockStatement()
+		if expression.Alternative = p.parseBlockStatement(); expression.Alternative == nil {
+			return nil
+		}
 	}
 	return expression
 }
 
-func (p *Parser) parseFunctionLiteral() ast.Expression {
-	literal := &ast.FunctionLiteral{Token: p.curToken}
+// parseWhileExpression parses a while loop: while (cond) { ... }
+func (p *Parser) parseWhileExpression() ast.Expression {
+	expression := &ast.WhileExpression{Token: p.curToken}
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+	p.nextToken()
+	expression.Condition = p.parseExpression(LOWEST)
+	if expression.Condition == nil || !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+	if expression.Body = p.parseLoopBody(); expression.Body == nil {
+		return nil
+	}
+	return expression
+}
+
+// parseForExpression parses a C-style for loop: for (let i = 0; i < n; i += 1) { ... },
+// or a for-in loop
+func (p *Parser) parseForExpression() ast.Expression {
+	expression := &ast.ForExpression{Token: p.curToken}
 	if !p.expectPeek(token.LPAREN) {
 		return nil
 	}
+	p.nextToken()
+	if p.curTokenIs(token.IDENT) && (p.peekTokenIs(token.IN) || p.peekTokenIs(token.COMMA)) {
+		return p.parseForInExpression(expression.Token)
+	}
+	if !p.curTokenIs(token.SEMICOLON) {
+		// the statement parsers consume the semicolon ending the statement
+		if expression.Init = p.parseStatement(); expression.Init == nil {
+			return nil
+		}
+		if !p.curTokenIs(token.SEMICOLON) && !p.expectPeek(token.SEMICOLON) {
+			return nil
+		}
+	}
+	if !p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+		if expression.Condition = p.parseExpression(LOWEST); expression.Condition == nil {
+			return nil
+		}
+	}
+	if !p.expectPeek(token.SEMICOLON) {
+		return nil
+	}
+	if !p.peekTokenIs(token.RPAREN) {
+		p.nextToken()
+		if expression.Update = p.parseExpression(LOWEST); expression.Update == nil {
+			return nil
+		}
+	}
+	if !p.expectPeek(token.RPAREN) || !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+	if expression.Body = p.parseLoopBody(); expression.Body == nil {
+		return nil
+	}
+	return expression
+}
 
-	literal.Parameters = p.parseFunctionParameters()
+// parseForInExpression parses the rest of a for-in loop, from its first variable:
+// for (x in arr) { ... } or for (k, v in hash) { ... }
+func (p *Parser) parseForInExpression(tok token.Token) ast.Expression {
+	expression := &ast.ForInExpression{Token: tok}
+	expression.Value = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	if p.peekTokenIs(token.COMMA) {
+		p.nextToken()
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		expression.Key = expression.Value
+		expression.Value = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	}
+	if !p.expectPeek(token.IN) {
+		return nil
+	}
+	p.nextToken()
+	expression.Iterable = p.parseExpression(LOWEST)
+	if expression.Iterable == nil || !p.expectPeek(token.RPAREN) || !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+	if expression.Body = p.parseLoopBody(); expression.Body == nil {
+		return nil
+	}
+	return expression
+}
 
-	if !p.expectPeek(token.LBRACE) {
+// parseLoopBody parses the block of a loop, in which break and continue are allowed
+func (p *Parser) parseLoopBody() *ast.BlockStatement {
+	p.loops++
+	defer func() { p.loops-- }()
+	return p.parseBlockStatement()
+}
+
+func (p *Parser) parseFunctionLiteral() ast.Expression {
+	if literal := p.parseFunction(&ast.FunctionLiteral{Token: p.curToken}); literal != nil {
+		return literal
+	}
+	return nil
+}
+
+// parseFunction parses the parameters and the body of a function, following its name
+// if it has one
+func (p *Parser) parseFunction(literal *ast.FunctionLiteral) *ast.FunctionLiteral {
+	if !p.expectPeek(token.LPAREN) {
 		return nil
 	}
+
+	if !p.parseFunctionParameters(literal) || !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	// break and continue can't reach the loops around the function
+	loops := p.loops
+	p.loops = 0
 	literal.Body = p.parseBlockStatement()
+	p.loops = loops
+	if literal.Body == nil {
+		return nil
+	}
 	return literal
 }
 
+// parseBlockStatement parses the statements up to the closing brace of the block,
+// skipping the statements holding errors
 func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 	block := &ast.BlockStatement{Token: p.curToken}
 	block.Statements = ast.Statements{}
 	p.nextToken()
+	depth := p.depth
 	for !p.curTokenIs(token.RBRACE) && !p.curTokenIs(token.EOF) {
 		stmt := p.parseStatement()
-		if stmt != nil {
-			block.Statements = append(block.Statements, stmt)
-
+		if p.panicking {
+			p.synchronize(depth)
+			continue
 		}
+		block.Statements = append(block.Statements, stmt)
 		p.nextToken()
 	}
+	if !p.curTokenIs(token.RBRACE) {
+		msg := fmt.Sprintf("expected %s to close the block, got %s instead", token.RBRACE, p.curToken.Type)
+		p.addError(UnexpectedToken, p.curToken, []token.Type{token.RBRACE}, msg)
+		return nil
+	}
+	block.EndToken = p.curToken
 	return block
 }
 
 // parseLetStatement parses a let statement
 func (p *Parser) parseLetStatement() *ast.LetStatement {
 	stmt := &ast.LetStatement{Token: p.curToken}
-	if !p.expectPeek(token.IDENT) {
-		return nil
+	if p.peekTokenIs(token.LBRACKET) || p.peekTokenIs(token.LBRACE) {
+		p.nextToken()
+		if stmt.Pattern = p.parsePattern(); stmt.Pattern == nil {
+			return nil
+		}
+	} else {
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
 	}
-	stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
 	if !p.expectPeek(token.ASSIGN) {
 		return nil
 	}
 
 	p.nextToken()
 
-	stmt.Value = p.parseExpression(LOWEST)
+	if stmt.Value = p.parseExpression(LOWEST); stmt.Value == nil {
+		return nil
+	}
 	if p.peekTokenIs(token.SEMICOLON) {
 		p.nextToken()
 	}
@@ -474,17 +938,150 @@ func (p *Parser) parseLetStatement() *ast.LetStatement {
 	return stmt
 }
 
+// parsePattern parses the target of a destructuring: an identifier, an array pattern
+// or a hash pattern
+func (p *Parser) parsePattern() ast.Expression {
+	switch p.curToken.Type {
+	case token.IDENT:
+		return &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	case token.LBRACKET:
+		if pattern := p.parseArrayPattern(); pattern != nil {
+			return pattern
+		}
+	case token.LBRACE:
+		if pattern := p.parseHashPattern(); pattern != nil {
+			return pattern
+		}
+	default:
+		expected := []token.Type{token.IDENT, token.LBRACKET, token.LBRACE}
+		msg := fmt.Sprintf("expected an identifier or a pattern, got %s instead", p.curToken.Type)
+		p.addError(UnexpectedToken, p.curToken, expected, msg)
+	}
+	return nil
+}
+
+// parsePatternElement parses the target of a pattern element, unless it is already set,
+// and its optional default value
+func (p *Parser) parsePatternElement(elem *ast.PatternElement) *ast.PatternElement {
+	if elem.Target == nil {
+		if elem.Target = p.parsePattern(); elem.Target == nil {
+			return nil
+		}
+	}
+	if p.peekTokenIs(token.ASSIGN) {
+		p.nextToken()
+		p.nextToken()
+		if elem.Default = p.parseExpression(LOWEST); elem.Default == nil {
+			return nil
+		}
+	}
+	return elem
+}
+
+// parseArrayPattern parses an array pattern: [a, [b, c], d = 1, ...rest]
+func (p *Parser) parseArrayPattern() *ast.ArrayPattern {
+	pattern := &ast.ArrayPattern{Token: p.curToken}
+	for !p.peekTokenIs(token.RBRACKET) {
+		p.nextToken()
+		if p.curTokenIs(token.ELLIPSIS) {
+			if !p.expectPeek(token.IDENT) {
+				return nil
+			}
+			pattern.Rest = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+			break
+		}
+		elem := p.parsePatternElement(&ast.PatternElement{})
+		if elem == nil {
+			return nil
+		}
+		pattern.Elements = append(pattern.Elements, elem)
+		if !p.peekTokenIs(token.RBRACKET) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+	if !p.expectPeek(token.RBRACKET) {
+		return nil
+	}
+	pattern.EndToken = p.curToken
+	return pattern
+}
+
+// parseHashPattern parses a hash pattern: {name, age: years, address: {city}, role = "user"}
+func (p *Parser) parseHashPattern() *ast.HashPattern {
+	pattern := &ast.HashPattern{Token: p.curToken}
+	for !p.peekTokenIs(token.RBRACE) {
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		elem := &ast.PatternElement{Key: &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}}
+		if p.peekTokenIs(token.COLON) {
+			p.nextToken()
+			p.nextToken()
+		} else {
+			elem.Target = elem.Key
+		}
+		if elem = p.parsePatternElement(elem); elem == nil {
+			return nil
+		}
+		pattern.Elements = append(pattern.Elements, elem)
+		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+	if !p.expectPeek(token.RBRACE) {
+		return nil
+	}
+	pattern.EndToken = p.curToken
+	return pattern
+}
+
 // parseReturnStatement parses a return statement
 func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
 	stmt := &ast.ReturnStatement{Token: p.curToken}
 
 	p.nextToken()
 
-	stmt.Value = p.parseExpression(LOWEST)
+	if stmt.Value = p.parseExpression(LOWEST); stmt.Value == nil {
+		return nil
+	}
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+// parseFunctionStatement parses a named function declaration: fn name(params) { body }
+func (p *Parser) parseFunctionStatement() *ast.FunctionStatement {
+	stmt := &ast.FunctionStatement{Token: p.curToken}
+	p.nextToken()
+	stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	if stmt.Function = p.parseFunction(&ast.FunctionLiteral{Token: stmt.Token}); stmt.Function == nil {
+		return nil
+	}
 	if p.peekTokenIs(token.SEMICOLON) {
 		p.nextToken()
 	}
+	return stmt
+}
 
+// parseBreakStatement parses a break or a continue statement
+func (p *Parser) parseBreakStatement() ast.Statement {
+	var stmt ast.Statement
+	if p.curTokenIs(token.BREAK) {
+		stmt = &ast.BreakStatement{Token: p.curToken}
+	} else {
+		stmt = &ast.ContinueStatement{Token: p.curToken}
+	}
+	if p.loops == 0 {
+		msg := fmt.Sprintf("%s is not in a loop", p.curToken.Literal)
+		p.addError(OutsideLoop, p.curToken, nil, msg)
+		return nil
+	}
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
 	return stmt
 }
 
@@ -492,59 +1089,122 @@ func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
 func (p *Parser) parseExpressionStatement() *ast.ExpressionStatement {
 	// defer untrace(trace("parseExpressionStatement"))
 	stmt := &ast.ExpressionStatement{Token: p.curToken}
-	stmt.Expression = p.parseExpression(LOWEST)
+	if stmt.Expression = p.parseExpression(LOWEST); stmt.Expression == nil {
+		return nil
+	}
 	if p.peekTokenIs(token.SEMICOLON) {
 		p.nextToken()
 	}
 	return stmt
 }
 
-// parseFunctionParameters parses a function's parameters
-func (p *Parser) parseFunctionParameters() ast.Identifiers {
+// parseFunctionParameters parses a function's parameters into the literal: names, each
+// optionally followed by a default value, and a trailing ...rest parameter
+func (p *Parser) parseFunctionParameters(literal *ast.FunctionLiteral) bool {
 	// defer untrace(trace("parseFunctionParameters"))
-	identifiers := ast.Identifiers{}
+	literal.Parameters = ast.Identifiers{}
 
 	if p.peekTokenIs(token.RPAREN) {
 		p.nextToken()
-		return identifiers
+		return true
 	}
-	p.nextToken()
-	identifiers = append(identifiers, p.parseIdentifier().(*ast.Identifier))
-	for p.peekTokenIs(token.COMMA) {
-		p.nextToken()
+	for {
+		if p.peekTokenIs(token.ELLIPSIS) {
+			p.nextToken()
+			if !p.expectPeek(token.IDENT) {
+				return false
+			}
+			literal.Rest = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+			if !p.peekTokenIs(token.RPAREN) {
+				msg := fmt.Sprintf("expected the rest parameter %s to be the last parameter, got %s after it",
+					literal.Rest.Value, p.peekToken.Type)
+				p.addError(UnexpectedToken, p.peekToken, []token.Type{token.RPAREN}, msg)
+				return false
+			}
+			break
+		}
+		if !p.expectPeek(token.IDENT) {
+			return false
+		}
+		param := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+		literal.Parameters = append(literal.Parameters, param)
+
+		if p.peekTokenIs(token.ASSIGN) {
+			p.nextToken()
+			p.nextToken()
+			def := p.parseExpression(LOWEST)
+			if def == nil {
+				return false
+			}
+			if literal.Defaults == nil {
+				literal.Defaults = map[string]ast.Expression{}
+			}
+			literal.Defaults[param.Value] = def
+		}
+		if !p.peekTokenIs(token.COMMA) {
+			break
+		}
 		p.nextToken()
-		identifiers = append(identifiers, p.parseIdentifier().(*ast.Identifier))
 	}
 
-	if !p.expectPeek(token.RPAREN) {
-		return nil
-	}
-
-	return identifiers
+	return p.expectPeek(token.RPAREN)
 }
 
-// parseStatement parses a statement
+// parseStatement parses a statement, returning nil if it holds an error, and attaches
+// to it the comments before its first token and those on the line of its last token
 func (p *Parser) parseStatement() ast.Statement {
+	leading := p.curToken.Comments
+	stmt := p.parseStatementNode()
+	if stmt != nil && (len(leading) > 0 || len(p.curToken.Trailing) > 0) {
+		p.attached[stmt] = &ast.StatementComments{Leading: leading, Trailing: p.curToken.Trailing}
+	}
+	return stmt
+}
+
+// parseStatementNode parses a statement according to its first token
+func (p *Parser) parseStatementNode() ast.Statement {
+	// the parse functions return typed pointers, which must not end up as non-nil
+	// interfaces holding a nil pointer
+	if p.curTokenIs(token.FUNCTION) && p.peekTokenIs(token.IDENT) {
+		if stmt := p.parseFunctionStatement(); stmt != nil {
+			return stmt
+		}
+		return nil
+	}
 	switch p.curToken.Type {
 	case token.LET:
-		return p.parseLetStatement()
+		if stmt := p.parseLetStatement(); stmt != nil {
+			return stmt
+		}
 	case token.RETURN:
-		return p.parseReturnStatement()
+		if stmt := p.parseReturnStatement(); stmt != nil {
+			return stmt
+		}
+	case token.BREAK, token.CONTINUE:
+		return p.parseBreakStatement()
 	default:
-		return p.parseExpressionStatement()
+		if stmt := p.parseExpressionStatement(); stmt != nil {
+			return stmt
+		}
 	}
+	return nil
 }
 
-// ParseProgram parses the input
+// ParseProgram parses the input. After an error, parsing resumes at the next statement,
+// and the statements holding errors are left out of the program
 func (p *Parser) ParseProgram() *ast.Program {
 	program := &ast.Program{}
 	program.Statements = ast.Statements{}
 	for !p.curTokenIs(token.EOF) {
 		stmt := p.parseStatement()
-		if stmt != nil {
-			program.Statements = append(program.Statements, stmt)
+		if p.panicking {
+			p.synchronize(0)
+			continue
 		}
+		program.Statements = append(program.Statements, stmt)
 		p.nextToken()
 	}
+	program.Comments = p.comments
+	program.Attached = p.attached
 	return program
 }
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"monkey/ast"
 	"monkey/lexer"
+	"monkey/token"
 	"testing"
 )
 
@@ -74,6 +75,61 @@ func TestLetStatementsWithReturn(t *testing.T) {
 	}
 }
 
+func TestLetPatternParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let [a, b] = xs;", "let [a, b] = xs;"},
+		{"let [a, b = 1 + 2, ...rest] = xs;", "let [a, b = (1 + 2), ...rest] = xs;"},
+		{"let [] = xs;", "let [] = xs;"},
+		{"let {name, age: years} = user;", "let {name, age: years} = user;"},
+		{`let {name = "x", address: {city}} = user;`, "let {name = x, address: {city}} = user;"},
+		{"let [{id}, [first, ...others] = []] = rows;", "let [{id}, [first, ...others] = []] = rows;"},
+	}
+	for _, tt := range tests {
+		l := lexer.NewLexer(tt.input)
+		p := NewParser(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt, ok := program.Statements[0].(*ast.LetStatement)
+		if !ok {
+			t.Fatalf("program.Statements[0] is not ast.LetStatement. got=%T", program.Statements[0])
+		}
+		if stmt.Name != nil || stmt.Pattern == nil {
+			t.Errorf("%q: expected a pattern, got name=%v", tt.input, stmt.Name)
+		}
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+
+	errorTests := []struct {
+		input    string
+		expected string
+	}{
+		{"let [a, 1] = xs;", "1:9: expected an identifier or a pattern, got INT instead"},
+		{"let [...rest, a] = xs;", "1:13: expected next token to be ], got , instead"},
+		{"let {1: a} = h;", "1:6: expected next token to be IDENT, got INT instead"},
+		{"let {a: } = h;", "1:9: expected an identifier or a pattern, got } instead"},
+		{"let [a b] = xs;", "1:8: expected next token to be ,, got IDENT instead"},
+	}
+	for _, tt := range errorTests {
+		l := lexer.NewLexer(tt.input)
+		p := NewParser(l)
+		p.ParseProgram()
+
+		errors := p.ParseErrors()
+		if len(errors) != 1 {
+			t.Fatalf("wrong number of errors for %q. got=%q", tt.input, p.Errors())
+		}
+		if errors[0].Error() != tt.expected {
+			t.Errorf("wrong error. expected=%q, got=%q", tt.expected, errors[0].Error())
+		}
+	}
+}
+
 func TestWrongLetStatements(t *testing.T) {
 	t.Skip("skipping failing wrong let statement test")
 	input := `
@@ -444,6 +500,138 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 			"2.0 % 4.0 * 5.0^2.0 - 2.0 / 4.0",
 			"(((2.0 % 4.0) * (5.0 ^ 2.0)) - (2.0 / 4.0))",
 		},
+		{
+			"1.05",
+			"1.05",
+		},
+		{
+			".5 + 0x10",
+			"(0.5 + 16)",
+		},
+		{
+			"1e3 * 1.5e-3",
+			"(1000 * 0.0015)",
+		},
+		{
+			"a = b = 5",
+			"(a = (b = 5))",
+		},
+		{
+			"x += y * 2",
+			"(x += (y * 2))",
+		},
+		{
+			"ok = a || b ?? c",
+			"(ok = ((a || b) ?? c))",
+		},
+		{
+			"a[i + 1] = b[0] * 2",
+			"((a[(i + 1)]) = ((b[0]) * 2))",
+		},
+		{
+			"h[\"k\"] += 1",
+			"((h[k]) += 1)",
+		},
+		{
+			"-x++ * ++y",
+			"((-(x++)) * (++y))",
+		},
+		{
+			"a[i]-- + h[\"k\"]++",
+			"(((a[i])--) + ((h[k])++))",
+		},
+		{
+			"--a[0]",
+			"(--(a[0]))",
+		},
+		{
+			"a ? b : c ? d : e",
+			"(a ? b : (c ? d : e))",
+		},
+		{
+			"x = a > b ? a + 1 : b * 2",
+			"(x = ((a > b) ? (a + 1) : (b * 2)))",
+		},
+		{
+			"a ?? b ? c : d || e",
+			"((a ?? b) ? c : (d || e))",
+		},
+		{
+			"f(a ? 1 : 2, b)",
+			"f((a ? 1 : 2), b)",
+		},
+		{
+			"a ? b ? 1 : 2 : 3",
+			"(a ? (b ? 1 : 2) : 3)",
+		},
+		{
+			"user.address.city",
+			"user.address.city",
+		},
+		{
+			"-user.age * 2",
+			"((-user.age) * 2)",
+		},
+		{
+			"arr.push(4).len()",
+			"arr.push(4).len()",
+		},
+		{
+			"users[0].name = \"x\"",
+			"((users[0]).name = x)",
+		},
+		{
+			"h.count++",
+			"(h.count++)",
+		},
+		{
+			"a[1:3]",
+			"(a[1:3])",
+		},
+		{
+			"a[:n - 1] + s[-2:]",
+			"((a[:(n - 1)]) + (s[(-2):]))",
+		},
+		{
+			"a[:][0]",
+			"((a[:])[0])",
+		},
+		{
+			"a | b & c",
+			"(a | (b & c))",
+		},
+		{
+			"a xor b << 2 == c",
+			"((a xor (b << 2)) == c)",
+		},
+		{
+			"~a & 0xff >> 4",
+			"(((~a) & 255) >> 4)",
+		},
+		{
+			"1 + 2 | 4",
+			"((1 + 2) | 4)",
+		},
+		{
+			"a && b || c",
+			"((a && b) || c)",
+		},
+		{
+			"a || b && c",
+			"(a || (b && c))",
+		},
+		{
+			"a ?? b || c == d",
+			"(a ?? (b || (c == d)))",
+		},
+		{
+			"!a && b < c",
+			"((!a) && (b < c))",
+		},
+		{
+			"1_000 - 0b11",
+			"(1000 - 3)",
+		},
 	}
 	for _, tt := range tests {
 		l := lexer.NewLexer(tt.input)
@@ -581,6 +769,44 @@ func TestFunctionLiteralParsing(t *testing.T) {
 	testInfixExpression(t, bodyStmt.Expression, "x", "+", "y")
 }
 
+func TestFunctionStatementParsing(t *testing.T) {
+	input := `fn add(x, y) { x + y; }; fn(z) { z }`
+	l := lexer.NewLexer(input)
+	p := NewParser(l)
+	program := p.ParseProgram()
+
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 2 {
+		t.Fatalf("program.Statements does not contain 2 statements. got=%d", len(program.Statements))
+	}
+
+	stmt, ok := program.Statements[0].(*ast.FunctionStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.FunctionStatement. got=%T", program.Statements[0])
+	}
+	if !testIdentifier(t, stmt.Name, "add") {
+		return
+	}
+	if len(stmt.Function.Parameters) != 2 {
+		t.Fatalf("function parameters wrong. want 2, got=%d", len(stmt.Function.Parameters))
+	}
+	testLiteralExpression(t, stmt.Function.Parameters[0], "x")
+	testLiteralExpression(t, stmt.Function.Parameters[1], "y")
+	if stmt.String() != "fn add(x, y) { (x + y); }" {
+		t.Errorf("stmt.String() wrong. got=%q", stmt.String())
+	}
+
+	// without a name, fn still starts a function literal
+	exp, ok := program.Statements[1].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("program.Statements[1] is not ast.ExpressionStatement. got=%T", program.Statements[1])
+	}
+	if _, ok := exp.Expression.(*ast.FunctionLiteral); !ok {
+		t.Fatalf("exp.Expression is not ast.FunctionLiteral. got=%T", exp.Expression)
+	}
+}
+
 func TestNoParamFunctionLiteralParsing(t *testing.T) {
 	input := `fn() { true; }`
 	l := lexer.NewLexer(input)
@@ -653,6 +879,81 @@ func TestFunctionParameterParsing(t *testing.T) {
 	}
 }
 
+func TestDefaultAndRestParameterParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"fn(a, b = 10) {}", "fn (a, b = 10) { };"},
+		{"fn(a = x + 1, b) {}", "fn (a = (x + 1), b) { };"},
+		{"fn(...args) {}", "fn (...args) { };"},
+		{"fn(a, b = [], ...rest) {}", "fn (a, b = [], ...rest) { };"},
+		{"fn sum(first, ...rest) {}", "fn sum(first, ...rest) { }"},
+	}
+	for _, tt := range tests {
+		l := lexer.NewLexer(tt.input)
+		p := NewParser(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+
+	errorTests := []struct {
+		input    string
+		expected string
+	}{
+		{"fn(...rest, a) {}", "1:11: expected the rest parameter rest to be the last parameter, got , after it"},
+		{"fn(a = ) {}", "1:8: no prefix parse function for ) found"},
+		{"fn(...) {}", "1:7: expected next token to be IDENT, got ) instead"},
+	}
+	for _, tt := range errorTests {
+		l := lexer.NewLexer(tt.input)
+		p := NewParser(l)
+		p.ParseProgram()
+
+		errors := p.ParseErrors()
+		if len(errors) != 1 {
+			t.Fatalf("wrong number of errors for %q. got=%q", tt.input, p.Errors())
+		}
+		if errors[0].Error() != tt.expected {
+			t.Errorf("wrong error. expected=%q, got=%q", tt.expected, errors[0].Error())
+		}
+	}
+}
+
+func TestSpreadExpressionParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"[...a, ...b, 3]", "[...a, ...b, 3]"},
+		{"f(1, ...args)", "f(1, ...args)"},
+		{"[...rest(a) + b]", "[...(rest(a) + b)]"},
+		{`{...base, "key": v, ...more}`, "{...base, key: v, ...more}"},
+	}
+	for _, tt := range tests {
+		l := lexer.NewLexer(tt.input)
+		p := NewParser(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+
+	// a spread is only allowed in a list or a hash
+	l := lexer.NewLexer("let x = ...a;")
+	p := NewParser(l)
+	p.ParseProgram()
+	if errors := p.ParseErrors(); len(errors) != 1 || errors[0].Code != MissingExpression {
+		t.Errorf("expected a missing expression error. got=%q", p.Errors())
+	}
+}
+
 func TestCallExpressionParsing(t *testing.T) {
 	input := "add(1, 2 * 3, 4 + 5);"
 	l := lexer.NewLexer(input)
@@ -752,6 +1053,47 @@ func TestParsingIndexExpressions(t *testing.T) {
 	}
 }
 
+func TestParsingSliceExpressions(t *testing.T) {
+	tests := []struct {
+		input string
+		low   interface{}
+		high  interface{}
+	}{
+		{"arr[1:3]", 1, 3},
+		{"arr[:n]", nil, "n"},
+		{"arr[2:]", 2, nil},
+		{"arr[:]", nil, nil},
+	}
+
+	for _, tt := range tests {
+		l := lexer.NewLexer(tt.input)
+		p := NewParser(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		slice, ok := stmt.Expression.(*ast.SliceExpression)
+		if !ok {
+			t.Fatalf("exp not *ast.SliceExpression. got=%T", stmt.Expression)
+		}
+		if !testIdentifier(t, slice.Left, "arr") {
+			return
+		}
+		for _, bound := range []struct {
+			exp      ast.Expression
+			expected interface{}
+		}{{slice.Low, tt.low}, {slice.High, tt.high}} {
+			if bound.expected == nil {
+				if bound.exp != nil {
+					t.Errorf("%q: bound is not nil. got=%s", tt.input, bound.exp)
+				}
+				continue
+			}
+			testLiteralExpression(t, bound.exp, bound.expected)
+		}
+	}
+}
+
 func TestParsingHashLiteralsStringKeys(t *testing.T) {
 	input := `{"one": 1, "two": 2, "three": 3}`
 	l := lexer.NewLexer(input)
@@ -1033,3 +1375,442 @@ func testIfExpression(t *testing.T, exp ast.Expression, cond string, cons string
 
 	return true
 }
+
+func TestNodePositions(t *testing.T) {
+	tests := []struct {
+		input       string
+		expectedPos string
+		expectedEnd string
+	}{
+		{"a + b * c", "1:1", "1:10"},
+		{"let x = [1, 2];", "1:1", "1:15"},
+		{"add(1,\n  2)", "1:1", "2:5"},
+		{"  arr[0]", "1:3", "1:9"},
+		{"if (x) { y }\nelse { z }", "1:1", "2:11"},
+		{"fn(x) {\n  x\n}", "1:1", "3:2"},
+		{`{"a": 1}`, "1:1", "1:9"},
+		{"-1.25", "1:1", "1:6"},
+	}
+	for _, tt := range tests {
+		l := lexer.NewLexer(tt.input)
+		p := NewParser(l)
+		program := p.ParseProgram()
+
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0]
+		if stmt.Pos().String() != tt.expectedPos {
+			t.Errorf("%q: wrong start position. expected=%s, got=%s",
+				tt.input, tt.expectedPos, stmt.Pos())
+		}
+		if stmt.End().String() != tt.expectedEnd {
+			t.Errorf("%q: wrong end position. expected=%s, got=%s",
+				tt.input, tt.expectedEnd, stmt.End())
+		}
+	}
+}
+
+func TestParserErrorPositions(t *testing.T) {
+	input := "let x = 5;\nlet = 10;"
+	l := lexer.NewFileLexer("main.mk", input)
+	p := NewParser(l)
+	p.ParseProgram()
+
+	errors := p.Errors()
+	if len(errors) == 0 {
+		t.Fatalf("expected parser errors, got none")
+	}
+	expected := "main.mk:2:5: expected next token to be IDENT, got = instead"
+	if errors[0] != expected {
+		t.Errorf("wrong error. expected=%q, got=%q", expected, errors[0])
+	}
+}
+
+func TestCommentsAreIgnored(t *testing.T) {
+	input := `
+	// the answer
+	let x = 42; /* not
+	a /* nested */ statement */
+	x * 2 // doubled
+	`
+	l := lexer.NewLexer(input)
+	p := NewParser(l)
+	program := p.ParseProgram()
+
+	checkParserErrors(t, p)
+
+	if program.String() != "let x = 42;(x * 2)" {
+		t.Errorf("program.String() wrong. got=%q", program.String())
+	}
+	if len(program.Comments) != 3 {
+		t.Fatalf("program.Comments does not contain 3 comments. got=%d",
+			len(program.Comments))
+	}
+	let := program.Statements[0].(*ast.LetStatement)
+	if len(let.Token.Comments) != 1 || let.Token.Comments[0].Text != "// the answer" {
+		t.Errorf("let statement comments wrong. got=%+v", let.Token.Comments)
+	}
+	if !program.Comments[1].IsBlock() {
+		t.Errorf("program.Comments[1] is not a block comment. got=%q", program.Comments[1].Text)
+	}
+}
+
+func TestStatementComments(t *testing.T) {
+	input := `// the answer
+	let x = 42; // why
+	// twice
+	let f = fn() {
+		// inside
+		x * 2 /* doubled */
+	}
+	f()
+	// at the end`
+	l := lexer.NewLexer(input)
+	p := NewParser(l)
+	program := p.ParseProgram()
+
+	checkParserErrors(t, p)
+
+	body := program.Statements[1].(*ast.LetStatement).Value.(*ast.FunctionLiteral).Body
+	tests := []struct {
+		stmt     ast.Statement
+		leading  []string
+		trailing []string
+	}{
+		{program.Statements[0], []string{"// the answer"}, []string{"// why"}},
+		{program.Statements[1], []string{"// twice"}, nil},
+		{body.Statements[0], []string{"// inside"}, []string{"/* doubled */"}},
+		{program.Statements[2], nil, nil},
+	}
+	for i, tt := range tests {
+		comments := program.Attached[tt.stmt]
+		if comments == nil {
+			if tt.leading != nil || tt.trailing != nil {
+				t.Errorf("tests[%d] - no comments attached to %q", i, tt.stmt)
+			}
+			continue
+		}
+		for _, group := range []struct {
+			got      []token.Comment
+			expected []string
+		}{{comments.Leading, tt.leading}, {comments.Trailing, tt.trailing}} {
+			if len(group.got) != len(group.expected) {
+				t.Errorf("tests[%d] - wrong comments. expected=%q, got=%+v", i, group.expected, group.got)
+				continue
+			}
+			for j, comment := range group.got {
+				if comment.Text != group.expected[j] {
+					t.Errorf("tests[%d] - comment[%d] wrong. expected=%q, got=%q", i, j, group.expected[j], comment.Text)
+				}
+			}
+		}
+	}
+	if len(program.Comments) != 6 {
+		t.Errorf("program.Comments does not contain 6 comments. got=%d", len(program.Comments))
+	}
+}
+
+func TestMalformedNumberErrors(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedError string
+	}{
+		{"let x = 0x;", `1:9: malformed number "0x": missing digits after base prefix`},
+		{"1 + 2e", `1:5: malformed number "2e": exponent has no digits`},
+		{"let y = 9223372036854775808;", `1:9: could not parse "9223372036854775808" as integer`},
+		{"5 @ 2", `1:3: illegal character '@'`},
+	}
+	for _, tt := range tests {
+		l := lexer.NewLexer(tt.input)
+		p := NewParser(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Errorf("%q: expected parser errors, got none", tt.input)
+			continue
+		}
+		if errors[0] != tt.expectedError {
+			t.Errorf("%q: wrong error. expected=%q, got=%q", tt.input, tt.expectedError, errors[0])
+		}
+	}
+}
+
+func TestStringLiteralErrors(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedError string
+	}{
+		{`let s = "bad \q escape";`, `1:9: unknown escape sequence "\\q"`},
+		{"let s = 1;\nputs(\"open", "2:6: unterminated string literal"},
+	}
+	for _, tt := range tests {
+		l := lexer.NewLexer(tt.input)
+		p := NewParser(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Errorf("%q: expected parser errors, got none", tt.input)
+			continue
+		}
+		if errors[0] != tt.expectedError {
+			t.Errorf("%q: wrong error. expected=%q, got=%q", tt.input, tt.expectedError, errors[0])
+		}
+	}
+}
+
+func TestInterpolatedStringParsing(t *testing.T) {
+	input := `"total: ${a + b} items, ${len(c)}"`
+	l := lexer.NewLexer(input)
+	p := NewParser(l)
+	program := p.ParseProgram()
+
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	str, ok := stmt.Expression.(*ast.InterpolatedString)
+	if !ok {
+		t.Fatalf("exp not *ast.InterpolatedString. got=%T", stmt.Expression)
+	}
+	if len(str.Parts) != 5 {
+		t.Fatalf("len(str.Parts) not 5. got=%d", len(str.Parts))
+	}
+	testInfixExpression(t, str.Parts[1], "a", "+", "b")
+	if str.String() != "total: ${(a + b)} items, ${len(c)}" {
+		t.Errorf("str.String() wrong. got=%q", str.String())
+	}
+	if str.End().Column != len(input)+1 {
+		t.Errorf("str.End() wrong. got=%s", str.End())
+	}
+}
+
+func TestStructuredParseErrors(t *testing.T) {
+	input := "let x = 5;\n\tlet = 10;"
+	l := lexer.NewFileLexer("main.mk", input)
+	p := NewParser(l)
+	p.ParseProgram()
+
+	errors := p.ParseErrors()
+	if len(errors) == 0 {
+		t.Fatalf("expected parser errors, got none")
+	}
+	err := errors[0]
+	if err.Code != UnexpectedToken {
+		t.Errorf("err.Code wrong. expected=%q, got=%q", UnexpectedToken, err.Code)
+	}
+	if len(err.Expected) != 1 || err.Expected[0] != token.IDENT {
+		t.Errorf("err.Expected wrong. got=%v", err.Expected)
+	}
+	if err.Found.Type != token.ASSIGN {
+		t.Errorf("err.Found wrong. got=%q", err.Found.Type)
+	}
+	if err.Pos.String() != "main.mk:2:6" {
+		t.Errorf("err.Pos wrong. got=%s", err.Pos)
+	}
+
+	expected := "main.mk:2:6: error[unexpected-token]: expected next token to be IDENT, got = instead\n" +
+		" 2 | \tlet = 10;\n" +
+		"   | \t    ^\n"
+	if rendered := err.Render(input); rendered != expected {
+		t.Errorf("err.Render wrong. expected=\n%s\ngot=\n%s", expected, rendered)
+	}
+}
+
+func TestSnippet(t *testing.T) {
+	source := "let s = \"héllo\";\nputs(s);"
+	tests := []struct {
+		pos      token.Position
+		end      token.Position
+		expected string
+	}{
+		{token.Position{Line: 1, Column: 9}, token.Position{Line: 1, Column: 16}, " 1 | let s = \"héllo\";\n   |         ^~~~~~~\n"},
+		{token.Position{Line: 2, Column: 1}, token.Position{Line: 3, Column: 1}, " 2 | puts(s);\n   | ^~~~~~~~\n"},
+		{token.Position{Line: 2, Column: 9}, token.Position{Line: 2, Column: 9}, " 2 | puts(s);\n   |         ^\n"},
+		{token.Position{Line: 3, Column: 1}, token.Position{Line: 3, Column: 1}, ""},
+	}
+	for i, tt := range tests {
+		if snippet := Snippet(source, tt.pos, tt.end); snippet != tt.expected {
+			t.Errorf("tests[%d] - wrong snippet. expected=%q, got=%q", i, tt.expected, snippet)
+		}
+	}
+}
+
+func TestParserErrorRecovery(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedErrors []string
+		expectedOutput string
+	}{
+		{
+			"let x 5;\nlet = 10;\nlet 838383;\nlet y = 1;",
+			[]string{
+				"1:7: expected next token to be =, got INT instead",
+				"2:5: expected next token to be IDENT, got = instead",
+				"3:5: expected next token to be IDENT, got INT instead",
+			},
+			"let y = 1;",
+		},
+		{
+			"let f = fn(a, 1) { return a; }; f(1);",
+			[]string{"1:15: expected next token to be IDENT, got INT instead"},
+			"f(1)",
+		},
+		{
+			"if (x +) { a; b; }\nlet z = 2;",
+			[]string{"1:8: no prefix parse function for ) found"},
+			"let z = 2;",
+		},
+		{
+			"let h = {\"b\" 2};\nlet z = 2;",
+			[]string{"1:14: expected next token to be :, got INT instead"},
+			"let z = 2;",
+		},
+		{
+			"let f = fn() { let = 1; return 2; }; f();",
+			[]string{"1:20: expected next token to be IDENT, got = instead"},
+			"let f = fn () { return 2;; };;f()",
+		},
+		{
+			"}\nlet a = 1;",
+			[]string{"1:1: no prefix parse function for } found"},
+			"let a = 1;",
+		},
+		{
+			"add(1, , 2); let q = 1;",
+			[]string{"1:8: no prefix parse function for , found"},
+			"let q = 1;",
+		},
+		{
+			"let f = fn() { 1",
+			[]string{"1:17: expected } to close the block, got EOF instead"},
+			"",
+		},
+		{
+			"[1, 2",
+			[]string{"1:6: expected next token to be ], got EOF instead"},
+			"",
+		},
+	}
+
+	for _, tt := range tests {
+		l := lexer.NewLexer(tt.input)
+		p := NewParser(l)
+		program := p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) != len(tt.expectedErrors) {
+			t.Errorf("wrong number of errors for %q. expected=%q, got=%q", tt.input, tt.expectedErrors, errors)
+			continue
+		}
+		for i, msg := range tt.expectedErrors {
+			if errors[i] != msg {
+				t.Errorf("wrong error for %q. expected=%q, got=%q", tt.input, msg, errors[i])
+			}
+		}
+		if program.String() != tt.expectedOutput {
+			t.Errorf("wrong program for %q. expected=%q, got=%q", tt.input, tt.expectedOutput, program.String())
+		}
+	}
+}
+
+func TestLoopParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"while (x < y) { x += 1; }", "while ((x < y)) { (x += 1) }"},
+		{"while (true) { break; continue; }", "while (true) { break;continue; }"},
+		{"for (let i = 0; i < n; i += 1) { puts(i) }", "for (let i = 0; (i < n); (i += 1)) { puts(i) }"},
+		{"for (;;) { break }", "for (; ; ) { break; }"},
+		{"for (i; i < 3;) { if (i) { continue } }", "for (i; (i < 3); ) { if (i) {  continue;; } }"},
+		{"for (x in [1, 2]) { puts(x) }", "for (x in [1, 2]) { puts(x) }"},
+		{"for (k, v in h) { puts(k, v) }", "for (k, v in h) { puts(k, v) }"},
+	}
+	for _, tt := range tests {
+		l := lexer.NewLexer(tt.input)
+		p := NewParser(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program.Statements does not contain 1 statement. got=%d", len(program.Statements))
+		}
+		if program.String() != tt.expected {
+			t.Errorf("wrong program. expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+}
+
+func TestBreakOutsideLoop(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"break;", "1:1: break is not in a loop"},
+		{"if (true) { continue }", "1:13: continue is not in a loop"},
+		{"while (true) { let f = fn() { break; }; }", "1:31: break is not in a loop"},
+	}
+	for _, tt := range tests {
+		l := lexer.NewLexer(tt.input)
+		p := NewParser(l)
+		p.ParseProgram()
+
+		errors := p.ParseErrors()
+		if len(errors) != 1 {
+			t.Fatalf("wrong number of errors for %q. got=%q", tt.input, p.Errors())
+		}
+		if errors[0].Code != OutsideLoop || errors[0].Error() != tt.expected {
+			t.Errorf("wrong error. expected=%q, got=%s %q", tt.expected, errors[0].Code, errors[0].Error())
+		}
+	}
+}
+
+func TestInvalidAssignment(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"1 = 2;", "1:3: cannot assign to 1"},
+		{"let x = 1; f() += x;", "1:16: cannot assign to f()"},
+		{"a + b = c", "1:7: cannot assign to (a + b)"},
+		{"5++", "1:2: cannot apply ++ to 5"},
+		{"--f()", "1:1: cannot apply -- to f()"},
+	}
+	for _, tt := range tests {
+		l := lexer.NewLexer(tt.input)
+		p := NewParser(l)
+		p.ParseProgram()
+
+		errors := p.ParseErrors()
+		if len(errors) != 1 {
+			t.Fatalf("wrong number of errors for %q. got=%q", tt.input, p.Errors())
+		}
+		if errors[0].Code != InvalidAssignment || errors[0].Error() != tt.expected {
+			t.Errorf("wrong error. expected=%q, got=%s %q", tt.expected, errors[0].Code, errors[0].Error())
+		}
+	}
+}
+
+func TestConditionalExpressionInHashLiteral(t *testing.T) {
+	input := `{"size": n > 10 ? "big" : "small"}`
+	l := lexer.NewLexer(input)
+	p := NewParser(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	hash, ok := stmt.Expression.(*ast.HashLiteral)
+	if !ok {
+		t.Fatalf("exp is not ast.HashLiteral. got=%T", stmt.Expression)
+	}
+	for _, value := range hash.Pairs {
+		cond, ok := value.(*ast.ConditionalExpression)
+		if !ok {
+			t.Fatalf("value is not ast.ConditionalExpression. got=%T", value)
+		}
+		if cond.String() != "((n > 10) ? big : small)" {
+			t.Errorf("wrong conditional. got=%q", cond.String())
+		}
+	}
+}
@@ -73,7 +73,7 @@ L:
 
 		// print errors if any
 		if len(p.Errors()) != 0 {
-			printParserErrors(out, p.Errors())
+			printParserErrors(out, line, p.ParseErrors())
 			continue
 		}
 
@@ -100,11 +100,11 @@ L:
 	}
 }
 
-func printParserErrors(out io.Writer, errors []string) {
+func printParserErrors(out io.Writer, source string, errors []*parser.ParseError) {
 	io.WriteString(out, MONKEYFROWN)
 	io.WriteString(out, "Woops! We ran into some monkey business here!\n")
 	io.WriteString(out, " parser errors:\n")
-	for _, msg := range errors {
-		io.WriteString(out, "\t"+msg+"\n")
+	for _, err := range errors {
+		io.WriteString(out, err.Render(source))
 	}
 }
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"monkey/evaluator"
+	"monkey/lexer"
+	"monkey/object"
+	"monkey/parser"
+)
+
+const runUsage = `usage: monkey run file
+
+Runs the script and prints its result, reporting syntax and runtime errors
+along with the offending source line.
+`
+
+// runCommand runs the run subcommand and returns the exit code
+func runCommand(args []string, stdout io.Writer, stderr io.Writer) int {
+	if len(args) != 1 {
+		io.WriteString(stderr, runUsage)
+		return 2
+	}
+
+	filename := args[0]
+	source, err := ioutil.ReadFile(filename)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	p := parser.NewParser(lexer.NewFileLexer(filename, string(source)))
+	program := p.ParseProgram()
+	if errors := p.ParseErrors(); len(errors) != 0 {
+		for _, err := range errors {
+			io.WriteString(stderr, err.Render(string(source)))
+		}
+		return 1
+	}
+
+	evaluated := evaluator.Eval(program, object.NewEnvironment())
+	if err, ok := evaluated.(*object.Error); ok {
+		fmt.Fprintf(stderr, "%s: runtime error: %s\n", err.Pos, err.Message)
+		io.WriteString(stderr, parser.Snippet(string(source), err.Pos, err.Pos))
+		return 1
+	}
+	if evaluated != nil && evaluated != evaluator.NULL {
+		fmt.Fprintln(stdout, evaluated.Inspect())
+	}
+	return 0
+}
@@ -1,12 +1,57 @@
 package token
 
+import (
+	"fmt"
+	"strings"
+)
+
 // Type the token type
 type Type string
 
+// Position describes a location in the source
+type Position struct {
+	Filename string `json:"filename,omitempty"` // name of the source file, if any
+	Offset   int    `json:"offset"`             // byte offset, starting at 0
+	Line     int    `json:"line"`               // line number, starting at 1
+	Column   int    `json:"column"`             // column number, starting at 1
+}
+
+// IsValid returns true if the position has been set
+func (p Position) IsValid() bool { return p.Line > 0 }
+
+// String string representation of the position: file:line:column or line:column
+func (p Position) String() string {
+	if !p.IsValid() {
+		if p.Filename != "" {
+			return p.Filename
+		}
+		return "-"
+	}
+	if p.Filename != "" {
+		return fmt.Sprintf("%s:%d:%d", p.Filename, p.Line, p.Column)
+	}
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
+
+// Comment a line (// ...) or block (/* ... */) comment kept as trivia
+type Comment struct {
+	Text string   `json:"text"` // the comment text, including its delimiters
+	Pos  Position `json:"pos"`  // position of the first char of the comment
+	End  Position `json:"end"`  // position immediately after the last char of the comment
+}
+
+// IsBlock returns true if this is a /* ... */ comment
+func (c Comment) IsBlock() bool { return strings.HasPrefix(c.Text, "/*") }
+
 // Token the token
 type Token struct {
-	Type    Type
-	Literal string
+	Type     Type      `json:"type"`
+	Literal  string    `json:"literal"`
+	Pos      Position  `json:"pos"`                // position of the first char of the token
+	End      Position  `json:"end"`                // position immediately after the last char of the token
+	Comments []Comment `json:"comments,omitempty"` // comments between the previous token and this one
+	Trailing []Comment `json:"trailing,omitempty"` // comments starting on the line of this token, after it
+	Error    string    `json:"error,omitempty"`    // for ILLEGAL tokens, what is wrong with the input
 }
 
 // Tokens list of tokens
@@ -23,13 +68,19 @@ func (t *Token) LookupIdent() {
 
 // keywords is the list of keywords of the programming language
 var keywords = map[string]Type{
-	"fn":     FUNCTION,
-	"let":    LET,
-	"true":   TRUE,
-	"false":  FALSE,
-	"if":     IF,
-	"else":   ELSE,
-	"return": RETURN,
+	"fn":       FUNCTION,
+	"let":      LET,
+	"true":     TRUE,
+	"false":    FALSE,
+	"if":       IF,
+	"else":     ELSE,
+	"return":   RETURN,
+	"while":    WHILE,
+	"for":      FOR,
+	"break":    BREAK,
+	"continue": CONTINUE,
+	"in":       IN,
+	"xor":      XOR,
 }
 
 const (
@@ -48,6 +99,12 @@ const (
 	BOOL = "BOOL" // let x = false
 	// STRING for strings
 	STRING = "STRING" // let x = "hello, world"
+	// STRINGSTART for the text opening an interpolated string
+	STRINGSTART = "STRINGSTART" // "total: ${
+	// STRINGMIDDLE for the text between two interpolations
+	STRINGMIDDLE = "STRINGMIDDLE" // } and ${
+	// STRINGEND for the text closing an interpolated string
+	STRINGEND = "STRINGEND" // } items"
 
 	// ASSIGN for assignment operator
 	ASSIGN = "="
@@ -73,6 +130,26 @@ const (
 	// NOTEQ for greater not equal to operator
 	NOTEQ = "!="
 
+	// BITAND for bitwise and operator
+	BITAND = "&"
+	// BITOR for bitwise or operator
+	BITOR = "|"
+	// BITNOT for bitwise not operator
+	BITNOT = "~"
+	// XOR for bitwise exclusive or operator, a keyword since ^ is the power operator
+	XOR = "xor"
+	// SHL for left shift operator
+	SHL = "<<"
+	// SHR for right shift operator
+	SHR = ">>"
+
+	// AND for logical and operator
+	AND = "&&"
+	// OR for logical or operator
+	OR = "||"
+	// NULLISH for null-coalescing operator
+	NULLISH = "??"
+
 	// LT for less than operator
 	LT = "<"
 	// GT for greater than operator
@@ -91,14 +168,23 @@ const (
 	// ASTERISKEQ for asterisk equal to operator
 	ASTERISKEQ = "*="
 
+	// INCREMENT for increment operator
+	INCREMENT = "++"
+	// DECREMENT for decrement operator
+	DECREMENT = "--"
+
 	// PERIOD for period symbol
 	PERIOD = "."
+	// ELLIPSIS for the rest parameter and spread symbol
+	ELLIPSIS = "..."
 	// COMMA for comma symbol
 	COMMA = ","
 	// SEMICOLON for semicolon symbol
 	SEMICOLON = ";"
 	// COLON for colon symbol
 	COLON = ":"
+	// QUESTION for question mark symbol
+	QUESTION = "?"
 	// LPAREN for left parenthesis symbol
 	LPAREN = "("
 	// RPAREN for right parsnthesis symbol
@@ -126,4 +212,14 @@ const (
 	ELSE = "ELSE"
 	// RETURN for return in functions
 	RETURN = "RETURN"
+	// WHILE for while loops
+	WHILE = "WHILE"
+	// FOR for for loops
+	FOR = "FOR"
+	// BREAK for leaving a loop
+	BREAK = "BREAK"
+	// CONTINUE for skipping to the next iteration of a loop
+	CONTINUE = "CONTINUE"
+	// IN for iterating over a collection: for (x in arr)
+	IN = "IN"
 )
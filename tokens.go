@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"monkey/lexer"
+	"monkey/token"
+	"os"
+	"text/tabwriter"
+)
+
+const tokensUsage = `usage: monkey tokens [-json] [file]
+
+Prints the tokens of the file, or of the standard input when no file is given,
+along with their positions.
+`
+
+// tokensCommand runs the tokens subcommand and returns the exit code
+func tokensCommand(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) int {
+	flags := flag.NewFlagSet("tokens", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		io.WriteString(stderr, tokensUsage)
+		flags.PrintDefaults()
+	}
+	asJSON := flags.Bool("json", false, "print the tokens as a JSON array")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() > 1 {
+		flags.Usage()
+		return 2
+	}
+
+	filename := ""
+	input := stdin
+	if flags.NArg() == 1 {
+		filename = flags.Arg(0)
+		file, err := os.Open(filename)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+		defer file.Close()
+		input = file
+	}
+
+	tokens := lexer.NewReaderLexer(filename, input).Tokens()
+	if *asJSON {
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		encoder.SetEscapeHTML(false)
+		if err := encoder.Encode(tokens); err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+		return 0
+	}
+	printTokenTable(stdout, tokens)
+	return 0
+}
+
+// printTokenTable prints the tokens as a table of positions, types and literals
+func printTokenTable(out io.Writer, tokens token.Tokens) {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "POSITION\tEND\tTYPE\tLITERAL\tERROR")
+	for _, tok := range tokens {
+		pos := fmt.Sprintf("%d:%d", tok.Pos.Line, tok.Pos.Column)
+		end := fmt.Sprintf("%d:%d", tok.End.Line, tok.End.Column)
+		fmt.Fprintf(w, "%s\t%s\t%s\t%q\t%s\n", pos, end, tok.Type, tok.Literal, tok.Error)
+	}
+	w.Flush()
+}